@@ -0,0 +1,251 @@
+package utilz
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by the time-dependent utilities,
+// so that they can be tested deterministically (see FakeClock).
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) ClockTicker
+	NewTimer(d time.Duration) ClockTimer
+}
+
+// ClockTicker is a time.Ticker created by a Clock.
+type ClockTicker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// ClockTimer is a time.Timer created by a Clock.
+type ClockTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the Clock backed by the time package.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) ClockTicker  { return realTicker{time.NewTicker(d)} }
+func (realClock) NewTimer(d time.Duration) ClockTimer    { return realTimer{time.NewTimer(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// orRealClock returns c, or RealClock if c is nil.
+func orRealClock(c Clock) Clock {
+	if c == nil {
+		return RealClock
+	}
+	return c
+}
+
+// FakeClock is a Clock whose time only moves when Advance or Set are called;
+// the timers, tickers and sleeps fire when the time reaches their deadline.
+// As with the time package, the tick channels have a buffer of one value,
+// and the ticks that can't be delivered are dropped. It is safe for concurrent use.
+type FakeClock struct {
+	mu      *sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+var _ Clock = &FakeClock{}
+
+// fakeWaiter is a timer (period == 0) or ticker (period > 0) of a FakeClock.
+type fakeWaiter struct {
+	clock    *FakeClock
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set at the provided time.
+func NewFakeClock(now time.Time) *FakeClock {
+	mu := &sync.Mutex{}
+	return &FakeClock{
+		mu:      mu,
+		changed: sync.NewCond(mu),
+		now:     now,
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep blocks until the clock has been advanced by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{
+		clock: c,
+		ch:    make(chan time.Time, 1),
+	}
+	c.schedule(w, d)
+	return w
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) ClockTicker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{
+		clock:  c,
+		period: d,
+		ch:     make(chan time.Time, 1),
+	}
+	c.schedule(w, d)
+	return fakeTicker{w}
+}
+
+// schedule adds (or re-adds) w to fire after d; must be called with c.mu held.
+func (c *FakeClock) schedule(w *fakeWaiter, d time.Duration) {
+	c.unschedule(w)
+	w.deadline = c.now.Add(d)
+	if d <= 0 && w.period == 0 {
+		// like time.NewTimer(0), fire right away.
+		c.fire(w)
+		return
+	}
+	c.waiters = append(c.waiters, w)
+	c.changed.Broadcast()
+}
+
+// unschedule removes w; must be called with c.mu held.
+func (c *FakeClock) unschedule(w *fakeWaiter) bool {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fire delivers a tick of w without blocking; must be called with c.mu held.
+func (c *FakeClock) fire(w *fakeWaiter) {
+	select {
+	case w.ch <- w.deadline:
+	default:
+		// the reader is slow; drop the tick.
+	}
+}
+
+// Advance moves the clock forward by d, firing (in order) the timers,
+// tickers and sleeps whose deadline is reached.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// Set moves the clock to t; if t is before the current time of the clock,
+// only the time is changed, and nothing fires.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(t)
+}
+
+// advanceTo must be called with c.mu held.
+func (c *FakeClock) advanceTo(target time.Time) {
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(target) {
+			break
+		}
+		w := c.waiters[0]
+		if w.deadline.After(c.now) {
+			c.now = w.deadline
+		}
+		c.fire(w)
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = target
+	c.changed.Broadcast()
+}
+
+// Waiters returns the number of active timers, tickers and sleeps.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until there are at least n active timers, tickers and sleeps;
+// it is used to make sure that a goroutine is waiting on the clock before advancing it.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.changed.Wait()
+	}
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.unschedule(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	active := w.clock.unschedule(w)
+	if w.period > 0 {
+		if d <= 0 {
+			panic("non-positive interval for ClockTicker.Reset")
+		}
+		w.period = d
+	}
+	w.clock.schedule(w, d)
+	return active
+}
+
+// fakeTicker adapts a periodic fakeWaiter to ClockTicker.
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time   { return t.w.ch }
+func (t fakeTicker) Stop()                 { t.w.Stop() }
+func (t fakeTicker) Reset(d time.Duration) { t.w.Reset(d) }
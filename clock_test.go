@@ -0,0 +1,221 @@
+package utilz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// expectNoTick fails the test if ch has a value ready.
+func expectNoTick(t *testing.T, ch <-chan time.Time) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected tick %v", v)
+	default:
+	}
+}
+
+func TestFakeClockTimer(t *testing.T) {
+	clock := newTestClock()
+	start := clock.Now()
+	timer := clock.NewTimer(time.Second)
+
+	clock.Advance(999 * time.Millisecond)
+	expectNoTick(t, timer.C())
+	clock.Advance(time.Millisecond)
+	if got, _ := receive(t, timer.C()); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("got %v", got)
+	}
+	if clock.Waiters() != 0 {
+		t.Fatal("a fired timer is still active")
+	}
+	if timer.Stop() {
+		t.Fatal("Stop of a fired timer returned true")
+	}
+
+	// Reset re-arms the timer from the current time:
+	if timer.Reset(time.Minute) {
+		t.Fatal("Reset of a fired timer returned true")
+	}
+	clock.Advance(30 * time.Second)
+	if !timer.Reset(time.Minute) {
+		t.Fatal("Reset of an active timer returned false")
+	}
+	clock.Advance(30 * time.Second)
+	expectNoTick(t, timer.C())
+	clock.Advance(30 * time.Second)
+	receive(t, timer.C())
+
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Fatal("Stop of an active timer returned false")
+	}
+	clock.Advance(time.Hour)
+	expectNoTick(t, stopped.C())
+
+	// like time.NewTimer, a non-positive duration fires right away:
+	receive(t, clock.NewTimer(0).C())
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	clock := newTestClock()
+	start := clock.Now()
+	ticker := clock.NewTicker(time.Second)
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		if got, _ := receive(t, ticker.C()); !got.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Fatalf("got tick %v at %v", got, i)
+		}
+	}
+	// the ticks that can't be delivered are dropped:
+	clock.Advance(5 * time.Second)
+	receive(t, ticker.C())
+	expectNoTick(t, ticker.C())
+
+	ticker.Reset(time.Minute)
+	clock.Advance(59 * time.Second)
+	expectNoTick(t, ticker.C())
+	clock.Advance(time.Second)
+	receive(t, ticker.C())
+
+	ticker.Stop()
+	if clock.Waiters() != 0 {
+		t.Fatal("the ticker is still active")
+	}
+	clock.Advance(time.Hour)
+	expectNoTick(t, ticker.C())
+}
+
+func TestFakeClockFiresInOrder(t *testing.T) {
+	clock := newTestClock()
+	start := clock.Now()
+	late := clock.NewTimer(3 * time.Second)
+	early := clock.NewTimer(time.Second)
+	ticker := clock.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(3 * time.Second)
+	if got, _ := receive(t, early.C()); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("got %v", got)
+	}
+	if got, _ := receive(t, ticker.C()); !got.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("got %v", got)
+	}
+	if got, _ := receive(t, late.C()); !got.Equal(start.Add(3 * time.Second)) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestFakeClockSet(t *testing.T) {
+	clock := newTestClock()
+	start := clock.Now()
+	timer := clock.NewTimer(time.Hour)
+
+	// moving back in time fires nothing:
+	clock.Set(start.Add(-time.Hour))
+	if !clock.Now().Equal(start.Add(-time.Hour)) {
+		t.Fatalf("got %v", clock.Now())
+	}
+	expectNoTick(t, timer.C())
+
+	clock.Set(start.Add(2 * time.Hour))
+	receive(t, timer.C())
+	if !clock.Now().Equal(start.Add(2 * time.Hour)) {
+		t.Fatalf("got %v", clock.Now())
+	}
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	clock := newTestClock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clock.Sleep(time.Minute)
+	}()
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned before the clock was advanced")
+	default:
+	}
+	clock.Advance(time.Minute)
+	receive(t, done)
+}
+
+func TestNewTimerWithClock(t *testing.T) {
+	clock := newTestClock()
+	elapsed := NewTimerWithClock(clock)
+	elapsedRaw := NewTimerRawWithClock(clock)
+
+	clock.Advance(90*time.Second + 600*time.Millisecond)
+	if got := elapsed(); got != 91*time.Second {
+		t.Fatalf("got %v", got)
+	}
+	if got := elapsedRaw(); got != 90*time.Second+600*time.Millisecond {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestKitchenTimeNowWithClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 15, 4, 5, 120*int(time.Millisecond), time.UTC))
+	if got := KitchenTimeNowWithClock(clock); got != "15:04:05" {
+		t.Fatalf("got %q", got)
+	}
+	if got := KitchenTimeMsNowWithClock(clock); got != "15:04:05.12" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestRetryBackoffWithClock(t *testing.T) {
+	failing := errors.New("failing")
+	cases := []struct {
+		name  string
+		retry func(task func() error, clock Clock) []error
+		// expected are the sleeps between the attempts.
+		expected []time.Duration
+	}{
+		{
+			name: "exponential",
+			retry: func(task func() error, clock Clock) []error {
+				return RetryExponentialBackoffWithClock(4, time.Second, task, clock)
+			},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name: "linear",
+			retry: func(task func() error, clock Clock) []error {
+				return RetryLinearBackoffWithClock(4, time.Second, task, clock)
+			},
+			expected: []time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clock := newTestClock()
+			var attempts []time.Time
+			done := make(chan []error)
+			go func() {
+				done <- c.retry(func() error {
+					attempts = append(attempts, clock.Now())
+					return failing
+				}, clock)
+			}()
+			for _, d := range c.expected {
+				clock.BlockUntil(1)
+				clock.Advance(d)
+			}
+			errs, _ := receive(t, done)
+
+			if len(attempts) != 4 || len(errs) != 4 {
+				t.Fatalf("got %v attempts and %v errors", len(attempts), len(errs))
+			}
+			for i, d := range c.expected {
+				if got := attempts[i+1].Sub(attempts[i]); got != d {
+					t.Fatalf("sleep %v is %v, expected %v", i, got, d)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package utilz
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aybabtme/rgbterm"
+)
+
+// ColorProfile is the set of colors supported by the terminal.
+type ColorProfile int32
+
+const (
+	// ColorProfileNone means no colors.
+	ColorProfileNone ColorProfile = iota
+	// ColorProfileANSI16 means the 16 basic ANSI colors.
+	ColorProfileANSI16
+	// ColorProfileANSI256 means the 256 xterm colors.
+	ColorProfileANSI256
+	// ColorProfileTrueColor means 24-bit RGB colors.
+	ColorProfileTrueColor
+)
+
+func (p ColorProfile) String() string {
+	switch p {
+	case ColorProfileNone:
+		return "none"
+	case ColorProfileANSI16:
+		return "16"
+	case ColorProfileANSI256:
+		return "256"
+	case ColorProfileTrueColor:
+		return "truecolor"
+	default:
+		return Sf("ColorProfile(%v)", int32(p))
+	}
+}
+
+// noColorProfileOverride means that the color profile is not overridden.
+const noColorProfileOverride = -1
+
+var colorProfileOverride int32 = noColorProfileOverride
+
+// SetColorProfile overrides the detected color profile (and the color mode);
+// this is mostly useful in tests.
+func SetColorProfile(p ColorProfile) {
+	atomic.StoreInt32(&colorProfileOverride, int32(p))
+}
+
+// ResetColorProfile removes the override set with SetColorProfile.
+func ResetColorProfile() {
+	atomic.StoreInt32(&colorProfileOverride, noColorProfileOverride)
+}
+
+func getColorProfileOverride() (ColorProfile, bool) {
+	p := atomic.LoadInt32(&colorProfileOverride)
+	if p == noColorProfileOverride {
+		return ColorProfileNone, false
+	}
+	return ColorProfile(p), true
+}
+
+// GetColorProfile returns the color profile used by the color helpers.
+func GetColorProfile() ColorProfile {
+	if p, ok := getColorProfileOverride(); ok {
+		return p
+	}
+	if !ColorEnabled() {
+		return ColorProfileNone
+	}
+	p := detectedColorProfile()
+	if p == ColorProfileNone {
+		// colors were explicitly enabled.
+		return ColorProfileANSI16
+	}
+	return p
+}
+
+var (
+	colorProfileOnce   sync.Once
+	colorProfileResult ColorProfile
+)
+
+func detectedColorProfile() ColorProfile {
+	colorProfileOnce.Do(func() {
+		colorProfileResult = DetectColorProfile()
+	})
+	return colorProfileResult
+}
+
+// DetectColorProfile returns the color profile of the terminal
+// as described by the FORCE_COLOR, COLORTERM and TERM environment variables.
+func DetectColorProfile() ColorProfile {
+	// FORCE_COLOR=1|2|3 as in the chalk/supports-color convention.
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		if level, err := strconv.Atoi(v); err == nil {
+			switch {
+			case level <= 0:
+				return ColorProfileNone
+			case level == 1:
+				return ColorProfileANSI16
+			case level == 2:
+				return ColorProfileANSI256
+			default:
+				return ColorProfileTrueColor
+			}
+		}
+	}
+
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	if colorTerm == "truecolor" || colorTerm == "24bit" {
+		return ColorProfileTrueColor
+	}
+
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case term == "dumb":
+		return ColorProfileNone
+	case strings.Contains(term, "truecolor"), strings.Contains(term, "24bit"), strings.Contains(term, "direct"):
+		return ColorProfileTrueColor
+	case strings.Contains(term, "256color"):
+		return ColorProfileANSI256
+	default:
+		return ColorProfileANSI16
+	}
+}
+
+// FgRGB colors the foreground of s with the color of the current
+// profile that is closest to the provided RGB color.
+func FgRGB(s string, r, g, b uint8) string {
+	return colorRGB(GetColorProfile(), s, r, g, b, true)
+}
+
+// BgRGB colors the background of s with the color of the current
+// profile that is closest to the provided RGB color.
+func BgRGB(s string, r, g, b uint8) string {
+	return colorRGB(GetColorProfile(), s, r, g, b, false)
+}
+
+func colorRGB(profile ColorProfile, s string, r, g, b uint8, foreground bool) string {
+	switch profile {
+	case ColorProfileTrueColor:
+		code := 38
+		if !foreground {
+			code = 48
+		}
+		return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm%s\x1b[0m", code, r, g, b, s)
+	case ColorProfileANSI256:
+		if foreground {
+			return rgbterm.FgString(s, r, g, b)
+		}
+		return rgbterm.BgString(s, r, g, b)
+	case ColorProfileANSI16:
+		code := ansi16Code(r, g, b)
+		if !foreground {
+			code += 10
+		}
+		return fmt.Sprintf("\x1b[%dm%s\x1b[0m", code, s)
+	default:
+		return s
+	}
+}
+
+// ansi16Palette are the (xterm) RGB values of the 16 basic ANSI colors,
+// indexed by the offset of their foreground code from 30 (or 90-8 for the bright ones).
+var ansi16Palette = [16][3]uint8{
+	{0, 0, 0},
+	{205, 0, 0},
+	{0, 205, 0},
+	{205, 205, 0},
+	{0, 0, 238},
+	{205, 0, 205},
+	{0, 205, 205},
+	{229, 229, 229},
+	{127, 127, 127},
+	{255, 0, 0},
+	{0, 255, 0},
+	{255, 255, 0},
+	{92, 92, 255},
+	{255, 0, 255},
+	{0, 255, 255},
+	{255, 255, 255},
+}
+
+// ansi16Code returns the foreground code (30-37, 90-97)
+// of the basic ANSI color closest to the RGB color.
+func ansi16Code(r, g, b uint8) int {
+	best := 0
+	bestDistance := -1
+	for i, c := range ansi16Palette {
+		dr := int(r) - int(c[0])
+		dg := int(g) - int(c[1])
+		db := int(b) - int(c[2])
+		// weighted by the sensitivity of the human eye:
+		distance := 3*dr*dr + 4*dg*dg + 2*db*db
+		if bestDistance < 0 || distance < bestDistance {
+			best = i
+			bestDistance = distance
+		}
+	}
+	if best < 8 {
+		return 30 + best
+	}
+	return 90 + best - 8
+}
@@ -0,0 +1,119 @@
+package utilz
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ColorMode controls whether the color helpers (Lime, RedBG, etc.)
+// and the log prefixes emit ANSI escape codes.
+type ColorMode int32
+
+const (
+	// ColorModeAuto enables colors only if both stdout and stderr are terminals,
+	// honoring the NO_COLOR, FORCE_COLOR and TERM=dumb environment variables.
+	ColorModeAuto ColorMode = iota
+	// ColorModeAlways always enables colors.
+	ColorModeAlways
+	// ColorModeNever always disables colors.
+	ColorModeNever
+)
+
+func (mode ColorMode) String() string {
+	switch mode {
+	case ColorModeAuto:
+		return "auto"
+	case ColorModeAlways:
+		return "always"
+	case ColorModeNever:
+		return "never"
+	default:
+		return Sf("ColorMode(%v)", int32(mode))
+	}
+}
+
+// ParseColorMode parses the value of a --color flag (auto, always, never).
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto", "":
+		return ColorModeAuto, nil
+	case "always", "on", "true", "yes":
+		return ColorModeAlways, nil
+	case "never", "off", "false", "no":
+		return ColorModeNever, nil
+	}
+	return ColorModeAuto, fmt.Errorf("unknown color mode: %q", s)
+}
+
+var colorMode int32 = int32(ColorModeAuto)
+
+// SetColorMode sets the global color mode.
+func SetColorMode(mode ColorMode) {
+	atomic.StoreInt32(&colorMode, int32(mode))
+}
+
+// GetColorMode returns the global color mode.
+func GetColorMode() ColorMode {
+	return ColorMode(atomic.LoadInt32(&colorMode))
+}
+
+// ColorEnabled returns true if the color helpers emit ANSI escape codes.
+func ColorEnabled() bool {
+	if p, ok := getColorProfileOverride(); ok {
+		return p != ColorProfileNone
+	}
+	switch GetColorMode() {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	default:
+		return autoColorEnabled()
+	}
+}
+
+var (
+	autoColorOnce   sync.Once
+	autoColorResult bool
+)
+
+func autoColorEnabled() bool {
+	autoColorOnce.Do(func() {
+		autoColorResult = detectColorSupport()
+	})
+	return autoColorResult
+}
+
+// detectColorSupport inspects the environment and the standard outputs
+// to decide whether colors should be used.
+func detectColorSupport() bool {
+	// See https://no-color.org/
+	if v, ok := os.LookupEnv("NO_COLOR"); ok && v != "" {
+		return false
+	}
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		switch strings.ToLower(v) {
+		case "0", "false", "no", "off":
+			return false
+		default:
+			return true
+		}
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(os.Stdout) && IsTerminal(os.Stderr)
+}
+
+// IsTerminal returns true if the file is a terminal.
+func IsTerminal(file *os.File) bool {
+	if file == nil {
+		return false
+	}
+	return terminal.IsTerminal(int(file.Fd()))
+}
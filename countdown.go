@@ -0,0 +1,202 @@
+package utilz
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CountdownTimer counts down a duration, and reports the remaining time at
+// each interval (aligned to the multiples of the interval, e.g. 10s, 9s, ..., 0s).
+// It can be paused and resumed, and it stops when the duration has passed,
+// when Stop is called, or when its context is done.
+type CountdownTimer struct {
+	mu       *sync.Mutex
+	clock    Clock
+	interval time.Duration
+	// remaining is the remaining time at since (if running).
+	remaining time.Duration
+	since     time.Time
+	paused    bool
+
+	c        chan time.Duration
+	changed  chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewCountdown starts a countdown of duration that reports the remaining
+// time every interval on C (if interval <= 0, only the start and the end are
+// reported).
+func NewCountdown(ctx context.Context, duration time.Duration, interval time.Duration) *CountdownTimer {
+	return NewCountdownWithClock(ctx, duration, interval, nil)
+}
+
+// NewCountdownWithClock is like NewCountdown, but uses the provided clock.
+func NewCountdownWithClock(ctx context.Context, duration time.Duration, interval time.Duration, clock Clock) *CountdownTimer {
+	clock = orRealClock(clock)
+	if duration < 0 {
+		duration = 0
+	}
+	if interval <= 0 {
+		interval = duration
+	}
+	cd := &CountdownTimer{
+		mu:        &sync.Mutex{},
+		clock:     clock,
+		interval:  interval,
+		remaining: duration,
+		since:     clock.Now(),
+		c:         make(chan time.Duration, 1),
+		changed:   make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go cd.run(ctx, duration)
+	return cd
+}
+
+// C returns the channel on which the remaining time is reported; it is
+// closed when the countdown ends (after reporting 0) or is stopped.
+// If the reader is slow, the stale values are replaced by the latest one.
+func (cd *CountdownTimer) C() <-chan time.Duration {
+	return cd.c
+}
+
+// Done returns a channel that is closed when the countdown has ended or stopped.
+func (cd *CountdownTimer) Done() <-chan struct{} {
+	return cd.done
+}
+
+// Remaining returns the remaining time.
+func (cd *CountdownTimer) Remaining() time.Duration {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	return cd.remainingLocked()
+}
+
+// remainingLocked must be called with cd.mu held.
+func (cd *CountdownTimer) remainingLocked() time.Duration {
+	if cd.paused {
+		return cd.remaining
+	}
+	remaining := cd.remaining - cd.clock.Now().Sub(cd.since)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Pause pauses the countdown; it does nothing if already paused.
+func (cd *CountdownTimer) Pause() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	if cd.paused {
+		return
+	}
+	cd.remaining = cd.remainingLocked()
+	cd.paused = true
+	cd.notify()
+}
+
+// Resume resumes the countdown; it does nothing if not paused.
+func (cd *CountdownTimer) Resume() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	if !cd.paused {
+		return
+	}
+	cd.since = cd.clock.Now()
+	cd.paused = false
+	cd.notify()
+}
+
+// Paused returns true if the countdown is paused.
+func (cd *CountdownTimer) Paused() bool {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	return cd.paused
+}
+
+// Stop stops the countdown, and waits for it to release its resources;
+// it is safe to call it multiple times.
+func (cd *CountdownTimer) Stop() {
+	cd.stopOnce.Do(func() {
+		close(cd.stop)
+	})
+	<-cd.done
+}
+
+// notify wakes up the countdown goroutine after a pause or resume.
+func (cd *CountdownTimer) notify() {
+	select {
+	case cd.changed <- struct{}{}:
+	default:
+	}
+}
+
+// nextDelay returns the delay until the next multiple of the interval.
+func (cd *CountdownTimer) nextDelay(remaining time.Duration) time.Duration {
+	if remaining <= 0 {
+		return 0
+	}
+	delay := remaining % cd.interval
+	if delay == 0 {
+		delay = cd.interval
+	}
+	return delay
+}
+
+func (cd *CountdownTimer) run(ctx context.Context, duration time.Duration) {
+	defer close(cd.done)
+	defer close(cd.c)
+
+	sendLatest(cd.c, duration)
+	if duration == 0 {
+		return
+	}
+
+	// next is the remaining time that will be reported at the next tick.
+	next := duration - cd.nextDelay(duration)
+	timer := cd.clock.NewTimer(cd.nextDelay(duration))
+	defer timer.Stop()
+	for {
+		var tick <-chan time.Time
+		if !cd.Paused() {
+			tick = timer.C()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-cd.stop:
+			return
+		case <-cd.changed:
+			if !timer.Stop() {
+				// drain the value that fired while pausing, if any.
+				select {
+				case <-timer.C():
+				default:
+				}
+			}
+			if !cd.Paused() {
+				remaining := cd.Remaining()
+				next = remaining - cd.nextDelay(remaining)
+				timer.Reset(cd.nextDelay(remaining))
+			}
+		case <-tick:
+			if cd.Paused() {
+				// paused while the tick was firing; the pause
+				// notification will reschedule the timer on resume.
+				continue
+			}
+			sendLatest(cd.c, next)
+			if next <= 0 {
+				return
+			}
+			delay := cd.nextDelay(next)
+			next -= delay
+			timer.Reset(delay)
+		}
+	}
+}
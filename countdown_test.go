@@ -0,0 +1,224 @@
+package utilz
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func newTestClock() *FakeClock {
+	return NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// receive returns the next value of ch, failing if none arrives in time.
+func receive[T any](t *testing.T, ch <-chan T) (T, bool) {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		return v, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a value")
+		panic("unreachable")
+	}
+}
+
+func TestTickerContextCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := NewTickerContextWithClock(ctx, time.Second, clock)
+
+	if tick, _ := receive(t, ticks); !tick.Equal(clock.Now()) {
+		t.Fatalf("got initial tick %v", tick)
+	}
+	for i := 1; i <= 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		if tick, _ := receive(t, ticks); tick.Sub(clock.Now()) != 0 {
+			t.Fatalf("got tick %v at %v", tick, clock.Now())
+		}
+	}
+
+	cancel()
+	for range ticks {
+	}
+	checkNoGoroutineLeak(t, base)
+	if n := clock.Waiters(); n != 0 {
+		t.Fatalf("the ticker was not stopped: %v waiters", n)
+	}
+}
+
+func TestTickerContextSlowReader(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := NewTickerContextWithClock(ctx, time.Second, clock)
+	receive(t, ticks)
+
+	// nobody reads: the ticks are dropped instead of blocking the ticker.
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	n := 0
+	for range ticks {
+		n++
+	}
+	if n > 1 {
+		t.Fatalf("got %v buffered ticks, expected at most 1", n)
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestCountdownRunsToZero(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	cd := NewCountdownWithClock(context.Background(), 3*time.Second, time.Second, clock)
+
+	for _, expected := range []time.Duration{3 * time.Second, 2 * time.Second, time.Second, 0} {
+		if got, _ := receive(t, cd.C()); got != expected {
+			t.Fatalf("got %v, expected %v", got, expected)
+		}
+		if expected > 0 {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+		}
+	}
+	if _, ok := receive(t, cd.C()); ok {
+		t.Fatal("channel not closed at the end")
+	}
+	<-cd.Done()
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestCountdownAlignsToInterval(t *testing.T) {
+	clock := newTestClock()
+	cd := NewCountdownWithClock(context.Background(), 5*time.Second, 2*time.Second, clock)
+	defer cd.Stop()
+
+	receive(t, cd.C())
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if got, _ := receive(t, cd.C()); got != 4*time.Second {
+		t.Fatalf("got %v, expected 4s", got)
+	}
+}
+
+func TestCountdownStop(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	cd := NewCountdownWithClock(context.Background(), time.Hour, time.Second, clock)
+	receive(t, cd.C())
+
+	cd.Stop()
+	cd.Stop() // must be safe
+	for range cd.C() {
+	}
+	checkNoGoroutineLeak(t, base)
+	if n := clock.Waiters(); n != 0 {
+		t.Fatalf("the timer was not stopped: %v waiters", n)
+	}
+}
+
+func TestCountdownCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cd := NewCountdownWithClock(ctx, time.Hour, time.Second, clock)
+
+	cancel()
+	<-cd.Done()
+	for range cd.C() {
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestCountdownPauseResume(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	cd := NewCountdownWithClock(context.Background(), 10*time.Second, time.Second, clock)
+	receive(t, cd.C())
+
+	clock.BlockUntil(1)
+	clock.Advance(1500 * time.Millisecond)
+	if got, _ := receive(t, cd.C()); got != 9*time.Second {
+		t.Fatalf("got %v, expected 9s", got)
+	}
+	cd.Pause()
+	if !cd.Paused() {
+		t.Fatal("not paused")
+	}
+	if got := cd.Remaining(); got != 8500*time.Millisecond {
+		t.Fatalf("remaining %v, expected 8.5s", got)
+	}
+	// the time passed while paused is not counted:
+	clock.Advance(time.Hour)
+	if got := cd.Remaining(); got != 8500*time.Millisecond {
+		t.Fatalf("remaining %v after pause, expected 8.5s", got)
+	}
+	select {
+	case v := <-cd.C():
+		t.Fatalf("got %v while paused", v)
+	default:
+	}
+
+	cd.Resume()
+	if cd.Paused() {
+		t.Fatal("still paused")
+	}
+	// the next tick is at the next multiple of the interval:
+	clock.BlockUntil(1)
+	clock.Advance(500 * time.Millisecond)
+	if got, _ := receive(t, cd.C()); got != 8*time.Second {
+		t.Fatalf("got %v, expected 8s", got)
+	}
+	if got := cd.Remaining(); got != 8*time.Second {
+		t.Fatalf("remaining %v, expected 8s", got)
+	}
+
+	cd.Stop()
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestCountdownSlowReader(t *testing.T) {
+	clock := newTestClock()
+	cd := NewCountdownWithClock(context.Background(), 10*time.Second, time.Second, clock)
+	defer cd.Stop()
+
+	for i := 0; i < 5; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	// wait for the last tick to be processed:
+	clock.BlockUntil(1)
+	// the stale values were replaced by the latest one:
+	if got, _ := receive(t, cd.C()); got != 5*time.Second {
+		t.Fatalf("got %v, expected 5s", got)
+	}
+}
+
+func TestLegacyCountdownDoesNotBlock(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := newTestClock()
+	remaining := Countdown(NewTickerCountdownWithClock(time.Second, clock), 3*time.Second)
+
+	// nobody reads until the countdown has ended; the ticks that arrive
+	// while the goroutine is busy are dropped, so keep ticking until it exits.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > base {
+		if time.Now().After(deadline) {
+			t.Fatal("the countdown did not end")
+		}
+		clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	n := 0
+	for range remaining {
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("got %v buffered values, expected only the latest", n)
+	}
+}
@@ -0,0 +1,191 @@
+package utilz
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DebugDumpOptions configures a debug dump.
+type DebugDumpOptions struct {
+	// Dir is the folder where the dump files are written (the os.TempDir if empty).
+	Dir string
+	// GroupStacks groups the goroutines with identical stacks, and prints
+	// each stack only once, with the number of goroutines that share it.
+	GroupStacks bool
+}
+
+// DebugDump writes a dump of the state of the program (goroutine stacks,
+// memory and GC stats, logger counters) to a new timestamped file
+// in opts.Dir, and returns the path of the file.
+func DebugDump(opts DebugDumpOptions) (string, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := CreateFolderIfNotExists(dir, 0755); err != nil {
+		return "", err
+	}
+	name := Sf("debug-dump_%s_%s.txt", logRunID, time.Now().Format(FilenameTimeFormat))
+	path := AddNumericSuffixIfFileExists(filepath.Join(dir, name))
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteDebugDump(file, opts.GroupStacks); err != nil {
+		file.Close()
+		return "", err
+	}
+	return path, file.Close()
+}
+
+// DumpOnDebugSignal writes a debug dump (see DebugDump) each time the program
+// receives a SIGUSR1, and logs the path of the file; stop stops the handling.
+func DumpOnDebugSignal(opts DebugDumpOptions) (stop func()) {
+	return OnDebugSignal(func() {
+		path, err := DebugDump(opts)
+		if err != nil {
+			Errorf("error while writing debug dump: %s", err)
+			return
+		}
+		Infof("debug dump written to %s", path)
+	})
+}
+
+// WriteDebugDump writes a dump of the state of the program to w.
+func WriteDebugDump(w io.Writer, groupStacks bool) error {
+	buf := new(bytes.Buffer)
+
+	buf.WriteString("=== program ===\n")
+	fmt.Fprintf(buf, "time: %s\n", time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(buf, "run id: %s\n", logRunID)
+	fmt.Fprintf(buf, "uptime: %s\n", formatDuration(time.Since(logStartedAt)))
+	fmt.Fprintf(buf, "log messages: %s\n", defaultLogger.MessageNumber())
+	fmt.Fprintf(buf, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(buf, "cpus: %d, GOMAXPROCS: %d\n", runtime.NumCPU(), runtime.GOMAXPROCS(0))
+	fmt.Fprintf(buf, "goroutines: %d\n", runtime.NumGoroutine())
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	buf.WriteString("\n=== memory ===\n")
+	fmt.Fprintf(buf, "alloc: %s\n", formatByteSize(mem.Alloc))
+	fmt.Fprintf(buf, "total alloc: %s\n", formatByteSize(mem.TotalAlloc))
+	fmt.Fprintf(buf, "sys: %s\n", formatByteSize(mem.Sys))
+	fmt.Fprintf(buf, "heap alloc: %s\n", formatByteSize(mem.HeapAlloc))
+	fmt.Fprintf(buf, "heap in use: %s\n", formatByteSize(mem.HeapInuse))
+	fmt.Fprintf(buf, "heap idle: %s\n", formatByteSize(mem.HeapIdle))
+	fmt.Fprintf(buf, "heap released: %s\n", formatByteSize(mem.HeapReleased))
+	fmt.Fprintf(buf, "heap objects: %d\n", mem.HeapObjects)
+	fmt.Fprintf(buf, "stack in use: %s\n", formatByteSize(mem.StackInuse))
+	fmt.Fprintf(buf, "mallocs: %d, frees: %d\n", mem.Mallocs, mem.Frees)
+
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+	buf.WriteString("\n=== gc ===\n")
+	fmt.Fprintf(buf, "num gc: %d\n", gc.NumGC)
+	if gc.NumGC > 0 {
+		fmt.Fprintf(buf, "last gc: %s (%s ago)\n", gc.LastGC.Format(time.RFC3339Nano), formatDuration(time.Since(gc.LastGC)))
+	}
+	fmt.Fprintf(buf, "total pause: %s\n", gc.PauseTotal)
+	if len(gc.Pause) > 0 {
+		fmt.Fprintf(buf, "last pause: %s\n", gc.Pause[0])
+	}
+	fmt.Fprintf(buf, "next gc at heap size: %s\n", formatByteSize(mem.NextGC))
+
+	stacks := allGoroutineStacks()
+	buf.WriteString("\n=== goroutines ===\n")
+	if groupStacks {
+		for _, group := range groupGoroutineStacks(stacks) {
+			fmt.Fprintf(buf, "%d goroutine(s): %s\n\n", group.count, group.stack)
+		}
+	} else {
+		buf.Write(stacks)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// allGoroutineStacks returns the stack traces of all the goroutines.
+func allGoroutineStacks() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+type goroutineStackGroup struct {
+	stack string
+	count int
+}
+
+var (
+	// e.g. "goroutine 18 [chan receive, 2 minutes]:"
+	goroutineHeaderRegex = regexp.MustCompile(`^goroutine \d+ \[([^,\]]+)[^\]]*\]:`)
+	// e.g. "main.work(0xc000012345, 0x1)"
+	goroutineArgsRegex = regexp.MustCompile(`\([^()]*\)$`)
+	// e.g. "created by main.main in goroutine 1"
+	goroutineCreatorRegex = regexp.MustCompile(` in goroutine \d+$`)
+)
+
+// groupGoroutineStacks groups the goroutines whose stacks are identical
+// (ignoring goroutine IDs, wait times and call arguments); the groups are
+// sorted by descending count.
+func groupGoroutineStacks(stacks []byte) []goroutineStackGroup {
+	counts := make(map[string]int)
+	var order []string
+	for _, block := range strings.Split(strings.TrimSpace(string(stacks)), "\n\n") {
+		lines := strings.Split(block, "\n")
+		for i, line := range lines {
+			switch {
+			case i == 0:
+				line = goroutineHeaderRegex.ReplaceAllString(line, "[$1]:")
+			case !strings.HasPrefix(line, "\t"):
+				line = goroutineArgsRegex.ReplaceAllString(line, "(...)")
+				line = goroutineCreatorRegex.ReplaceAllString(line, "")
+			}
+			lines[i] = line
+		}
+		key := strings.Join(lines, "\n")
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+
+	groups := make([]goroutineStackGroup, len(order))
+	for i, key := range order {
+		groups[i] = goroutineStackGroup{stack: key, count: counts[key]}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].count > groups[j].count
+	})
+	return groups
+}
+
+// formatByteSize formats a number of bytes with binary units (e.g. "1.5 MiB").
+func formatByteSize(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return Sf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return Sf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
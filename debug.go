@@ -10,16 +10,12 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
-	"github.com/aybabtme/rgbterm"
 	tm "github.com/buger/goterm"
 	"github.com/davecgh/go-spew/spew"
-	"github.com/hako/durafmt"
 )
 
 // TODO: number to color
@@ -45,13 +41,15 @@ func Errorln(a ...interface{}) (n int, err error) {
 	return fmt.Fprintln(os.Stderr, a...)
 }
 
+// The prefixes of the log levels; LogLevel.Prefix colors them
+// when the messages are written, so that ColorMode changes apply.
 var (
 	DebugPrefix   string = "[DEBU]"
 	InfoPrefix    string = "[INFO]"
-	SuccessPrefix string = Lime("[SUCC]")
-	WarnPrefix    string = Yellow("[WARN]")
-	ErrorPrefix   string = RedBG("[ERRO]")
-	FatalPrefix   string = RedBG("[FATAL]")
+	SuccessPrefix string = "[SUCC]"
+	WarnPrefix    string = "[WARN]"
+	ErrorPrefix   string = "[ERRO]"
+	FatalPrefix   string = "[FATAL]"
 )
 var (
 	LogIncludeLevel bool = true
@@ -78,8 +76,9 @@ func getBaseFilename(filename string) string {
 type LogHeaderParameter func() string
 
 // LogParamCallStack adds the file and line number of the log call to the log message.
+// In the header of a Logger, the location is taken from the log call itself.
 func LogParamCallStack() string {
-	file, line := GetCallerLocation(4)
+	file, line := GetCallerLocation(5)
 	return Sf("%s:%v", file, line)
 }
 
@@ -95,53 +94,30 @@ func LogParamTimestampMs() string {
 
 // LogMessageNumber adds the index of the log call (incremental) to the log header.
 func LogMessageNumber() string {
-	return strconv.FormatInt(atomic.LoadInt64(&logMessageCounter), 10)
+	return defaultLogger.MessageNumber()
 }
 
 // LogElapsedFromStart adds the time passed from the program start.
 func LogElapsedFromStart() string {
-	return formatDuration(time.Now().Sub(logStartedAt))
+	return defaultLogger.ElapsedFromStart()
 }
 
 // LogElapsedFromLastLogMessage adds to the log header the time passed from the last log call.
 func LogElapsedFromLastLogMessage() string {
-	return formatDuration(time.Now().Sub(logLastMessageRunTime))
+	return defaultLogger.ElapsedFromLastLogMessage()
 }
 func formatDuration(d time.Duration) string {
-	d = d.Truncate(time.Millisecond)
-	dur := durafmt.Parse(d).String()
-	dur = strings.Replace(dur, " ", "", -1)
-
-	{
-		dur = strings.Replace(dur, "micros", "µs", 1)
-		dur = strings.Replace(dur, "milliseconds", "ms", 1)
-		dur = strings.Replace(dur, "seconds", "s", 1)
-		dur = strings.Replace(dur, "minutes", "m", 1)
-		dur = strings.Replace(dur, "hours", "h", 1)
-		dur = strings.Replace(dur, "days", "D", 1)
-		dur = strings.Replace(dur, "weeks", "W", 1)
-		dur = strings.Replace(dur, "years", "Y", 1)
-	}
-	{
-		dur = strings.Replace(dur, "millisecond", "ms", 1)
-		dur = strings.Replace(dur, "second", "s", 1)
-		dur = strings.Replace(dur, "minute", "m", 1)
-		dur = strings.Replace(dur, "hour", "h", 1)
-		dur = strings.Replace(dur, "day", "D", 1)
-		dur = strings.Replace(dur, "week", "W", 1)
-		dur = strings.Replace(dur, "year", "Y", 1)
-	}
-	return dur
+	return FormatDurationCompact(d, time.Millisecond, 0)
 }
 
 // LogRunID adds to the log header the unique ID of this program run.
 func LogRunID() string {
 	return logRunID
 }
-func newParamsWithLogLevel(level string) []LogHeaderParameter {
+func newParamsWithLogLevel(level LogLevel) []LogHeaderParameter {
 	return []LogHeaderParameter{
 		func() string {
-			return level
+			return level.Prefix()
 		},
 	}
 }
@@ -158,184 +134,271 @@ var DefaultLogParameters = []LogHeaderParameter{
 
 // Static:
 var (
-	logMu        = &sync.Mutex{}
 	logStartedAt = time.Now()
 	logRunID     = strings.ToUpper(RandomString(5))
 )
 
-// Dynamic:
-var (
-	logMessageCounter     int64
-	logLastMessageRunTime time.Time
-)
-
 func DebugfWithParameters(params []LogHeaderParameter, format string, a ...interface{}) {
-	header := getHeader(params)
-
-	fmt.Fprintln(
-		os.Stderr,
-		header,
+	defaultLogger.output(
+		LogLevelDebug,
+		params,
 		fmt.Sprintf(
 			format,
 			a...,
 		),
+		nil,
 	)
 }
 func DebuglnWithParameters(params []LogHeaderParameter, a ...interface{}) {
-	header := getHeader(params)
-
-	fmt.Fprintln(
-		os.Stderr,
-		header,
+	defaultLogger.output(
+		LogLevelDebug,
+		params,
 		fmt.Sprintln(
 			a...,
 		),
+		nil,
 	)
 }
-func getHeader(params []LogHeaderParameter) string {
-	logMu.Lock()
-	defer logMu.Unlock()
-
-	var headerPrefix string
-	{
-		var headerVals []string
-		for _, v := range params {
-			headerVals = append(headerVals, v())
-		}
-		headerPrefix = strings.Join(headerVals, "|")
-	}
-
-	var headerVals []string
-	for _, v := range DefaultLogParameters {
-		headerVals = append(headerVals, v())
-	}
-	header := strings.Join(headerVals, "|")
-	header = headerPrefix + "[" + header + "]"
-
-	{
-		logMessageCounter++
-		logLastMessageRunTime = time.Now()
-	}
-
-	return header
-}
 func Debugf(format string, a ...interface{}) {
-	DebugfWithParameters(
-		newParamsWithLogLevel(DebugPrefix),
-		format,
-		a...,
+	defaultLogger.output(
+		LogLevelDebug,
+		newParamsWithLogLevel(LogLevelDebug),
+		fmt.Sprintf(format, a...),
+		nil,
 	)
 }
 func Infof(format string, a ...interface{}) {
-	DebugfWithParameters(
-		newParamsWithLogLevel(InfoPrefix),
-		format,
-		a...,
+	defaultLogger.output(
+		LogLevelInfo,
+		newParamsWithLogLevel(LogLevelInfo),
+		fmt.Sprintf(format, a...),
+		nil,
 	)
 }
 func Successf(format string, a ...interface{}) {
-	DebugfWithParameters(
-		newParamsWithLogLevel(SuccessPrefix),
-		format,
-		a...,
+	defaultLogger.output(
+		LogLevelSuccess,
+		newParamsWithLogLevel(LogLevelSuccess),
+		fmt.Sprintf(format, a...),
+		nil,
 	)
 }
 func Warnf(format string, a ...interface{}) {
-	DebugfWithParameters(
-		newParamsWithLogLevel(WarnPrefix),
-		format,
-		a...,
+	defaultLogger.output(
+		LogLevelWarn,
+		newParamsWithLogLevel(LogLevelWarn),
+		fmt.Sprintf(format, a...),
+		nil,
 	)
 }
 
 func Errorf(format string, a ...interface{}) {
-	DebugfWithParameters(
-		newParamsWithLogLevel(ErrorPrefix),
-		RedBG(format),
-		a...,
+	defaultLogger.output(
+		LogLevelError,
+		newParamsWithLogLevel(LogLevelError),
+		fmt.Sprintf(RedBG(format), a...),
+		nil,
 	)
 }
 func Fatalf(format string, a ...interface{}) {
-	DebugfWithParameters(
+	defaultLogger.output(
+		LogLevelFatal,
 		append(
-			newParamsWithLogLevel(FatalPrefix),
+			newParamsWithLogLevel(LogLevelFatal),
 			LogParamCallStack,
 		),
-		RedBG(format),
-		a...,
+		fmt.Sprintf(RedBG(format), a...),
+		nil,
 	)
 	os.Exit(1)
 }
 func Fataln(a ...interface{}) {
-	DebuglnWithParameters(
+	defaultLogger.output(
+		LogLevelFatal,
+		append(
+			newParamsWithLogLevel(LogLevelFatal),
+			LogParamCallStack,
+		),
+		fmt.Sprintln(a...),
+		nil,
+	)
+	os.Exit(1)
+}
+
+// With returns a child of the default logger that adds the provided
+// key/value pairs to every message.
+func With(keyvals ...interface{}) *Logger {
+	return defaultLogger.With(keyvals...)
+}
+func Debugw(msg string, keyvals ...interface{}) {
+	defaultLogger.output(
+		LogLevelDebug,
+		newParamsWithLogLevel(LogLevelDebug),
+		msg,
+		keyvals,
+	)
+}
+func Infow(msg string, keyvals ...interface{}) {
+	defaultLogger.output(
+		LogLevelInfo,
+		newParamsWithLogLevel(LogLevelInfo),
+		msg,
+		keyvals,
+	)
+}
+func Successw(msg string, keyvals ...interface{}) {
+	defaultLogger.output(
+		LogLevelSuccess,
+		newParamsWithLogLevel(LogLevelSuccess),
+		msg,
+		keyvals,
+	)
+}
+func Warnw(msg string, keyvals ...interface{}) {
+	defaultLogger.output(
+		LogLevelWarn,
+		newParamsWithLogLevel(LogLevelWarn),
+		msg,
+		keyvals,
+	)
+}
+func Errorw(msg string, keyvals ...interface{}) {
+	defaultLogger.output(
+		LogLevelError,
+		newParamsWithLogLevel(LogLevelError),
+		RedBG(msg),
+		keyvals,
+	)
+}
+func Fatalw(msg string, keyvals ...interface{}) {
+	defaultLogger.output(
+		LogLevelFatal,
 		append(
-			newParamsWithLogLevel(FatalPrefix),
+			newParamsWithLogLevel(LogLevelFatal),
 			LogParamCallStack,
 		),
-		a...,
+		RedBG(msg),
+		keyvals,
 	)
 	os.Exit(1)
 }
 
 func Black(s string) string {
-	return rgbterm.FgString(s, 0, 0, 0)
+	if !ColorEnabled() {
+		return s
+	}
+	return FgRGB(s, 0, 0, 0)
 }
 func White(s string) string {
-	return rgbterm.FgString(s, 255, 255, 255)
+	if !ColorEnabled() {
+		return s
+	}
+	return FgRGB(s, 255, 255, 255)
 }
 func BlackBG(s string) string {
-	return rgbterm.BgString(s, 0, 0, 0)
+	if !ColorEnabled() {
+		return s
+	}
+	return BgRGB(s, 0, 0, 0)
 }
 func WhiteBG(s string) string {
-	return Black(rgbterm.BgString(s, 255, 255, 255))
+	if !ColorEnabled() {
+		return s
+	}
+	return Black(BgRGB(s, 255, 255, 255))
 }
 func Lime(str string) string {
-	return rgbterm.FgString(str, 252, 255, 43)
+	if !ColorEnabled() {
+		return str
+	}
+	return FgRGB(str, 252, 255, 43)
 }
 func LimeBG(str string) string {
-	return Black(rgbterm.BgString(str, 252, 255, 43))
+	if !ColorEnabled() {
+		return str
+	}
+	return Black(BgRGB(str, 252, 255, 43))
 }
 func Yellow(message string) string {
+	if !ColorEnabled() {
+		return message
+	}
 	return tm.Color(message, tm.YELLOW)
 }
 func YellowBG(message string) string {
+	if !ColorEnabled() {
+		return message
+	}
 	return Black(tm.Background(message, tm.YELLOW))
 }
 func Orange(message string) string {
-	return rgbterm.FgString(message, 255, 165, 0)
+	if !ColorEnabled() {
+		return message
+	}
+	return FgRGB(message, 255, 165, 0)
 }
 func OrangeBG(message string) string {
-	return Black(rgbterm.BgString(message, 255, 165, 0))
+	if !ColorEnabled() {
+		return message
+	}
+	return Black(BgRGB(message, 255, 165, 0))
 }
 func Red(str string) string {
-	return rgbterm.FgString(str, 255, 0, 0)
+	if !ColorEnabled() {
+		return str
+	}
+	return FgRGB(str, 255, 0, 0)
 }
 func RedBG(s string) string {
+	if !ColorEnabled() {
+		return s
+	}
 	return tm.Color(tm.Background(s, tm.RED), tm.WHITE)
 }
 
 // light blue?
 func Shakespeare(str string) string {
-	return rgbterm.FgString(str, 82, 179, 217)
+	if !ColorEnabled() {
+		return str
+	}
+	return FgRGB(str, 82, 179, 217)
 }
 func ShakespeareBG(str string) string {
-	return White(rgbterm.BgString(str, 82, 179, 217))
+	if !ColorEnabled() {
+		return str
+	}
+	return White(BgRGB(str, 82, 179, 217))
 }
 
 func Purple(s string) string {
-	return rgbterm.FgString(s, 255, 0, 255)
+	if !ColorEnabled() {
+		return s
+	}
+	return FgRGB(s, 255, 0, 255)
 }
 func PurpleBG(s string) string {
-	return Black(rgbterm.BgString(s, 255, 0, 255))
+	if !ColorEnabled() {
+		return s
+	}
+	return Black(BgRGB(s, 255, 0, 255))
 }
 func Indigo(s string) string {
-	return rgbterm.FgString(s, 75, 0, 130)
+	if !ColorEnabled() {
+		return s
+	}
+	return FgRGB(s, 75, 0, 130)
 }
 func IndigoBG(s string) string {
-	return rgbterm.BgString(s, 75, 0, 130)
+	if !ColorEnabled() {
+		return s
+	}
+	return BgRGB(s, 75, 0, 130)
 }
 
 func Bold(message string) string {
+	if !ColorEnabled() {
+		return message
+	}
 	return tm.Bold(message)
 }
 
@@ -390,6 +453,9 @@ func MustGetenv(key string) string {
 }
 
 func StringToColor(str string) func(string) string {
+	if !ColorEnabled() {
+		return noColor
+	}
 	r, g, b, _ := calcColor(HashString(str))
 
 	bgColor := WhiteBG
@@ -397,10 +463,13 @@ func StringToColor(str string) func(string) string {
 		bgColor = BlackBG
 	}
 	return func(str string) string {
-		return bgColor(rgbterm.FgString(str, uint8(r), uint8(g), uint8(b)))
+		return bgColor(FgRGB(str, uint8(r), uint8(g), uint8(b)))
 	}
 }
 func StringToColorBG(str string) func(string) string {
+	if !ColorEnabled() {
+		return noColor
+	}
 	r, g, b, _ := calcColor(HashString(str))
 
 	textColor := White
@@ -408,9 +477,12 @@ func StringToColorBG(str string) func(string) string {
 		textColor = Black
 	}
 	return func(str string) string {
-		return textColor(rgbterm.BgString(str, uint8(r), uint8(g), uint8(b)))
+		return textColor(BgRGB(str, uint8(r), uint8(g), uint8(b)))
 	}
 }
+func noColor(str string) string {
+	return str
+}
 func Colorize(str string) string {
 	colorizer := StringToColor(str)
 	return colorizer(str)
@@ -465,6 +537,23 @@ func (ce *CombinedErrors) Error() string {
 	}
 	return buf.String()
 }
+
+// Errors returns the non-nil errors.
+func (ce *CombinedErrors) Errors() []error {
+	errs := make([]error, 0, len(ce.errs))
+	for _, err := range ce.errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Unwrap returns the non-nil errors, so that errors.Is and errors.As
+// match any of the combined errors (including nested CombinedErrors).
+func (ce *CombinedErrors) Unwrap() []error {
+	return ce.Errors()
+}
 func allNil(errs ...error) bool {
 	for _, err := range errs {
 		if err != nil {
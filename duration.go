@@ -0,0 +1,198 @@
+package utilz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// Day is 24 hours (it does not account for daylight saving time).
+	Day = 24 * time.Hour
+	// Week is 7 days.
+	Week = 7 * Day
+	// Year is 365 days.
+	Year = 365 * Day
+)
+
+// durationUnits are the units used by FormatDurationCompact, largest first.
+var durationUnits = []struct {
+	symbol string
+	size   time.Duration
+}{
+	{"Y", Year},
+	{"W", Week},
+	{"D", Day},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"µs", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// parseDurationUnits maps the units accepted by ParseDurationExtended to their size.
+var parseDurationUnits = map[string]time.Duration{
+	"Y":  Year,
+	"y":  Year,
+	"W":  Week,
+	"w":  Week,
+	"D":  Day,
+	"d":  Day,
+	"h":  time.Hour,
+	"m":  time.Minute,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"µs": time.Microsecond, // U+00B5 micro sign
+	"μs": time.Microsecond, // U+03BC Greek letter mu
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// FormatDurationCompact formats d in a compact form, like "1Y5W5s" or "1m3s250ms"
+// (Y is 365 days, W is 7 days, D is 24 hours); zero units are omitted.
+// The duration is truncated to precision (e.g. time.Millisecond; if <= 0,
+// nothing is truncated), and if maxUnits > 0, at most maxUnits units are shown,
+// starting from the largest one (e.g. "1h2m" instead of "1h2m3s" with maxUnits = 2).
+func FormatDurationCompact(d time.Duration, precision time.Duration, maxUnits int) string {
+	if precision > 0 {
+		d = d.Truncate(precision)
+	}
+	if d == 0 {
+		return "0s"
+	}
+
+	buf := new(strings.Builder)
+	// work on the absolute value as uint64, so that math.MinInt64 is fine too.
+	abs := uint64(d)
+	if d < 0 {
+		buf.WriteString("-")
+		abs = -abs
+	}
+	units := 0
+	for _, unit := range durationUnits {
+		if maxUnits > 0 && units >= maxUnits {
+			break
+		}
+		size := uint64(unit.size)
+		if abs < size {
+			continue
+		}
+		buf.WriteString(Sf("%d%s", abs/size, unit.symbol))
+		abs %= size
+		units++
+		if abs == 0 {
+			break
+		}
+	}
+	return buf.String()
+}
+
+// ErrInvalidDuration is returned by ParseDurationExtended for malformed durations.
+var ErrInvalidDuration = errors.New("invalid duration")
+
+// ParseDurationExtended parses a duration like time.ParseDuration does (e.g.
+// "1h30m", "-1.5s", "250ms"), but also accepts days ("D" or "d"), weeks
+// ("W" or "w") and years ("Y" or "y", 365 days); it can parse the output
+// of FormatDurationCompact (e.g. "1Y5W5s").
+func ParseDurationExtended(s string) (time.Duration, error) {
+	orig := s
+	invalid := func() (time.Duration, error) {
+		return 0, fmt.Errorf("%w %q", ErrInvalidDuration, orig)
+	}
+	if s == "" {
+		return invalid()
+	}
+
+	negative := false
+	if s[0] == '-' || s[0] == '+' {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return invalid()
+	}
+
+	var total uint64
+	for s != "" {
+		// the number (with an optional fraction):
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		intPart := s[:i]
+		s = s[i:]
+		fracPart := ""
+		if s != "" && s[0] == '.' {
+			i = 1
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			fracPart = s[1:i]
+			s = s[i:]
+			if fracPart == "" && intPart == "" {
+				return invalid()
+			}
+		} else if intPart == "" {
+			return invalid()
+		}
+
+		// the unit:
+		i = 0
+		for i < len(s) && s[i] != '.' && (s[i] < '0' || s[i] > '9') {
+			i++
+		}
+		unit, ok := parseDurationUnits[s[:i]]
+		if !ok {
+			return invalid()
+		}
+		s = s[i:]
+
+		value, ok := parseDurationComponent(intPart, fracPart, uint64(unit))
+		if !ok || total+value < total || total+value > 1<<63 {
+			return invalid()
+		}
+		total += value
+	}
+	if negative {
+		return -time.Duration(total), nil
+	}
+	if total > math.MaxInt64 {
+		return invalid()
+	}
+	return time.Duration(total), nil
+}
+
+// parseDurationComponent returns (intPart.fracPart * unit) nanoseconds;
+// returns false on overflow.
+func parseDurationComponent(intPart, fracPart string, unit uint64) (uint64, bool) {
+	var v uint64
+	for _, c := range intPart {
+		if v > (math.MaxUint64-9)/10 {
+			return 0, false
+		}
+		v = v*10 + uint64(c-'0')
+	}
+	if v != 0 && v > (1<<63)/unit {
+		return 0, false
+	}
+	v *= unit
+
+	// like time.ParseDuration, the fraction is converted with float64 math:
+	frac, scale := uint64(0), 1.0
+	for _, c := range fracPart {
+		if frac > (1<<63-1)/10 {
+			// ignore the digits that would overflow.
+			break
+		}
+		frac = frac*10 + uint64(c-'0')
+		scale *= 10
+	}
+	v += uint64(float64(frac) * (float64(unit) / scale))
+	return v, v <= 1<<63
+}
@@ -0,0 +1,137 @@
+package utilz
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestFormatDurationCompact(t *testing.T) {
+	cases := []struct {
+		d         time.Duration
+		precision time.Duration
+		maxUnits  int
+		expected  string
+	}{
+		{0, 0, 0, "0s"},
+		{500 * time.Microsecond, time.Millisecond, 0, "0s"},
+		{time.Millisecond, time.Millisecond, 0, "1ms"},
+		{63250 * time.Millisecond, time.Millisecond, 0, "1m3s250ms"},
+		{-90 * time.Second, time.Millisecond, 0, "-1m30s"},
+		{400*Day + 5*time.Second, time.Millisecond, 0, "1Y5W5s"},
+		{8 * Day, 0, 0, "1W1D"},
+		{time.Hour + 2*time.Minute + 3*time.Second, 0, 2, "1h2m"},
+		{1234567 * time.Microsecond, 10 * time.Millisecond, 0, "1s230ms"},
+		{1500 * time.Nanosecond, 0, 0, "1µs500ns"},
+		{math.MaxInt64, 0, 0, "292Y24W3D23h47m16s854ms775µs807ns"},
+		{math.MinInt64, 0, 0, "-292Y24W3D23h47m16s854ms775µs808ns"},
+	}
+	for _, c := range cases {
+		if got := FormatDurationCompact(c.d, c.precision, c.maxUnits); got != c.expected {
+			t.Errorf("FormatDurationCompact(%v, %v, %v) = %q, expected %q", int64(c.d), c.precision, c.maxUnits, got, c.expected)
+		}
+	}
+}
+
+func TestFormatDurationMatchesFormatDurationCompact(t *testing.T) {
+	for _, d := range []time.Duration{0, time.Nanosecond, 1500 * time.Microsecond, 63250 * time.Millisecond, 400 * Day} {
+		if got, expected := formatDuration(d), FormatDurationCompact(d, time.Millisecond, 0); got != expected {
+			t.Errorf("formatDuration(%v) = %q, expected %q", d, got, expected)
+		}
+	}
+}
+
+func TestParseDurationExtended(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected time.Duration
+		invalid  bool
+	}{
+		{s: "1D2h", expected: 26 * time.Hour},
+		{s: "1d2h", expected: 26 * time.Hour},
+		{s: "2W", expected: 14 * Day},
+		{s: "1y", expected: Year},
+		{s: "1Y5W5s", expected: 400*Day + 5*time.Second},
+		{s: ".5s", expected: 500 * time.Millisecond},
+		{s: "1.5h", expected: 90 * time.Minute},
+		{s: "1.s", expected: time.Second},
+		{s: "-1m30s", expected: -90 * time.Second},
+		{s: "+3µs", expected: 3 * time.Microsecond},
+		{s: "3μs", expected: 3 * time.Microsecond},
+		{s: "3us", expected: 3 * time.Microsecond},
+		{s: "0", expected: 0},
+		{s: "-0", expected: 0},
+		{s: "9223372036854775807ns", expected: math.MaxInt64},
+		{s: "-9223372036854775808ns", expected: math.MinInt64},
+		{s: "1h.", invalid: true},
+		{s: "", invalid: true},
+		{s: "-", invalid: true},
+		{s: "1", invalid: true},
+		{s: ".s", invalid: true},
+		{s: "1.5.5s", invalid: true},
+		{s: "1h30", invalid: true},
+		{s: "1x", invalid: true},
+		{s: "1 h", invalid: true},
+		{s: "9223372036854775808ns", invalid: true},
+		{s: "300Y", invalid: true},
+		{s: "99999999999999999999h", invalid: true},
+	}
+	for _, c := range cases {
+		got, err := ParseDurationExtended(c.s)
+		if c.invalid {
+			if !errors.Is(err, ErrInvalidDuration) {
+				t.Errorf("ParseDurationExtended(%q) = %v, %v; expected ErrInvalidDuration", c.s, got, err)
+			}
+			continue
+		}
+		if err != nil || got != c.expected {
+			t.Errorf("ParseDurationExtended(%q) = %v, %v; expected %v", c.s, got, err, c.expected)
+		}
+	}
+}
+
+func TestParseDurationExtendedMatchesTimeParseDuration(t *testing.T) {
+	// inputs that only use the units accepted by time.ParseDuration:
+	inputs := []string{
+		"0", "-0", "+0", "1ns", "1us", "1µs", "1μs", "1ms", "1s", "1m", "1h",
+		"1h30m", "-1.5h", "+2.25s", ".5s", "1.s", "1.000000001s", "0.1ns",
+		"2562047h47m16.854775807s", "-2562047h47m16.854775808s",
+		"2562047h47m16.854775808s", "1h.", "", "1", ".", "1x", "1.2.3s", "s",
+		"-", "1hh",
+	}
+	for _, s := range inputs {
+		expected, expectedErr := time.ParseDuration(s)
+		got, err := ParseDurationExtended(s)
+		if (err != nil) != (expectedErr != nil) || got != expected {
+			t.Errorf("ParseDurationExtended(%q) = %v, %v; time.ParseDuration = %v, %v", s, got, err, expected, expectedErr)
+		}
+	}
+}
+
+func TestDurationRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	durations := []time.Duration{0, 1, -1, math.MaxInt64, math.MinInt64, Year, -Week, Day + time.Nanosecond}
+	for i := 0; i < 10000; i++ {
+		d := time.Duration(r.Int63())
+		// also cover the small durations:
+		d /= time.Duration(1) << uint(r.Intn(63))
+		if r.Intn(2) == 0 {
+			d = -d
+		}
+		durations = append(durations, d)
+	}
+	for _, d := range durations {
+		formatted := FormatDurationCompact(d, 0, 0)
+		got, err := ParseDurationExtended(formatted)
+		if err != nil || got != d {
+			t.Fatalf("round trip of %v (%q) = %v, %v", int64(d), formatted, int64(got), err)
+		}
+		// the output of time.Duration.String is accepted too:
+		got, err = ParseDurationExtended(d.String())
+		if err != nil || got != d {
+			t.Fatalf("parsing %q = %v, %v", d.String(), int64(got), err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package utilz
+
+import (
+	"sync"
+)
+
+// ErrorCollector accumulates the errors returned by concurrent tasks;
+// it is safe for concurrent use.
+//
+// Errors with the same message are stored once, with a count;
+// when the cap is reached, errors with new messages are dropped and counted.
+type ErrorCollector struct {
+	mu        *sync.Mutex
+	wg        *sync.WaitGroup
+	maxErrors int
+
+	errs    []error
+	counts  []int
+	indexes map[string]int
+
+	total   int
+	dropped int
+}
+
+// NewErrorCollector returns a new ErrorCollector that stores at most
+// maxErrors distinct errors; if maxErrors <= 0, there is no limit.
+func NewErrorCollector(maxErrors int) *ErrorCollector {
+	return &ErrorCollector{
+		mu:        &sync.Mutex{},
+		wg:        &sync.WaitGroup{},
+		maxErrors: maxErrors,
+		indexes:   make(map[string]int),
+	}
+}
+
+// Add adds an error to the collector; nil errors are ignored.
+func (c *ErrorCollector) Add(err error) {
+	if err == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total++
+	msg := err.Error()
+	if index, ok := c.indexes[msg]; ok {
+		c.counts[index]++
+		return
+	}
+	if c.maxErrors > 0 && len(c.errs) >= c.maxErrors {
+		c.dropped++
+		return
+	}
+	c.indexes[msg] = len(c.errs)
+	c.errs = append(c.errs, err)
+	c.counts = append(c.counts, 1)
+}
+
+// Go calls f in a new goroutine, and adds its error (if any) to the collector;
+// a panic is recovered and added as a *PanicError. Use Wait to wait for the
+// goroutines to return.
+func (c *ErrorCollector) Go(f func() error) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.Add(callRecover(f))
+	}()
+}
+
+// Wait blocks until all the function calls from the Go method have returned,
+// then returns the result of Err.
+func (c *ErrorCollector) Wait() error {
+	c.wg.Wait()
+	return c.Err()
+}
+
+// Errors returns the distinct errors that were stored, in order of first occurrence.
+func (c *ErrorCollector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.errs...)
+}
+
+// Count returns how many times an error with the same message as err was added.
+func (c *ErrorCollector) Count(err error) int {
+	if err == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index, ok := c.indexes[err.Error()]; ok {
+		return c.counts[index]
+	}
+	return 0
+}
+
+// Len returns the number of distinct errors that were stored.
+func (c *ErrorCollector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errs)
+}
+
+// Total returns the number of non-nil errors that were added (duplicates and dropped included).
+func (c *ErrorCollector) Total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Dropped returns the number of errors that were not stored
+// because the cap was reached.
+func (c *ErrorCollector) Dropped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// Err returns nil if no errors were added; otherwise it returns a *CombinedErrors
+// whose messages include the repetition counts and the number of dropped errors.
+// The original errors can still be matched with errors.Is and errors.As.
+func (c *ErrorCollector) Err() error {
+	errs := c.annotatedErrors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return CombineErrors(errs...)
+}
+
+// Format renders the collected errors like FormatErrorArray does.
+func (c *ErrorCollector) Format(prefix string) string {
+	return FormatErrorArray(prefix, c.annotatedErrors())
+}
+
+func (c *ErrorCollector) annotatedErrors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := make([]error, 0, len(c.errs)+1)
+	for i, err := range c.errs {
+		if c.counts[i] > 1 {
+			errs = append(errs, &RepeatedError{Err: err, Count: c.counts[i]})
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	if c.dropped > 0 {
+		errs = append(errs, &DroppedErrors{Count: c.dropped})
+	}
+	return errs
+}
+
+// RepeatedError is an error that occurred multiple times.
+type RepeatedError struct {
+	Err   error
+	Count int
+}
+
+func (e *RepeatedError) Error() string {
+	return Sf("%s (x%v)", e.Err, e.Count)
+}
+
+func (e *RepeatedError) Unwrap() error {
+	return e.Err
+}
+
+// DroppedErrors reports the number of errors that were not stored
+// by an ErrorCollector because its cap was reached.
+type DroppedErrors struct {
+	Count int
+}
+
+func (e *DroppedErrors) Error() string {
+	return Sf("%v more errors were dropped", e.Count)
+}
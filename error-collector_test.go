@@ -0,0 +1,110 @@
+package utilz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestErrorCollectorConcurrentAdd(t *testing.T) {
+	const (
+		goroutines = 8
+		perRoutine = 100
+		maxErrors  = 5
+		distinct   = 10
+	)
+	c := NewErrorCollector(maxErrors)
+	wg := &sync.WaitGroup{}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perRoutine; i++ {
+				c.Add(fmt.Errorf("error %v", i%distinct))
+				c.Add(nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := c.Total(); got != goroutines*perRoutine {
+		t.Fatalf("got total %v, expected %v", got, goroutines*perRoutine)
+	}
+	if got := c.Len(); got != maxErrors {
+		t.Fatalf("got %v stored errors, expected %v", got, maxErrors)
+	}
+	stored := 0
+	for _, err := range c.Errors() {
+		// every stored message was seen by every goroutine:
+		if got := c.Count(err); got != goroutines*perRoutine/distinct {
+			t.Errorf("got count %v for %q", got, err)
+		}
+		stored += c.Count(err)
+	}
+	if got := c.Dropped(); got != goroutines*perRoutine-stored {
+		t.Fatalf("got %v dropped, expected %v", got, goroutines*perRoutine-stored)
+	}
+}
+
+func TestErrorCollectorErr(t *testing.T) {
+	c := NewErrorCollector(2)
+	if c.Err() != nil {
+		t.Fatal("got an error from an empty collector")
+	}
+	first, second, third := errors.New("first"), errors.New("second"), errors.New("third")
+	c.Add(first)
+	c.Add(first)
+	c.Add(second)
+	c.Add(third)
+
+	err := c.Err()
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("the original errors can't be matched: %v", err)
+	}
+	var repeated *RepeatedError
+	if !errors.As(err, &repeated) || repeated.Err != first || repeated.Count != 2 {
+		t.Fatalf("got %+v", repeated)
+	}
+	var dropped *DroppedErrors
+	if !errors.As(err, &dropped) || dropped.Count != 1 {
+		t.Fatalf("got %+v", dropped)
+	}
+	formatted := c.Format("errors:")
+	for _, expected := range []string{"first (x2)", "second", "1 more errors were dropped"} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("%q not in %q", expected, formatted)
+		}
+	}
+}
+
+func TestErrorCollectorGo(t *testing.T) {
+	c := NewErrorCollector(0)
+	const n = 50
+	release := make(chan struct{})
+	started := &sync.WaitGroup{}
+	started.Add(n)
+	for i := 0; i < n; i++ {
+		c.Go(func() error {
+			// all the functions must be running at the same time:
+			started.Done()
+			<-release
+			return errors.New("failed")
+		})
+	}
+	started.Wait()
+	close(release)
+
+	c.Go(func() error {
+		panic("boom")
+	})
+	err := c.Wait()
+	if got := c.Total(); got != n+1 {
+		t.Fatalf("got total %v, expected %v", got, n+1)
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("got %v, expected a *PanicError", err)
+	}
+}
@@ -1,20 +1,28 @@
 package utilz
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 )
 
 type OsSignalHandler func(os.Signal) bool
 
+// DefaultSignals are the signals that ask the program to terminate:
+// SIGINT (Ctrl+C) and SIGTERM (sent by Kubernetes, systemd, docker stop, etc.);
+// SIGKILL can't be caught.
+var DefaultSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
 // Notify calls handler when gets specified signals and pass given signal to
 // handler. If handler returns false, notify stops waiting for signals.
 func Notify(handler OsSignalHandler, signals ...os.Signal) {
 	pipe := make(chan os.Signal, 1)
 	signal.Notify(pipe, signals...)
+	defer signal.Stop(pipe)
 
 	for sign := range pipe {
 		if !handler(sign) {
@@ -23,7 +31,7 @@ func Notify(handler OsSignalHandler, signals ...os.Signal) {
 	}
 }
 func DefaultNotify(handler func(os.Signal) bool) {
-	Notify(handler, os.Interrupt, os.Kill)
+	Notify(handler, DefaultSignals...)
 }
 
 func WaitSystemSignal(messages ...string) {
@@ -42,33 +50,84 @@ func HardWaitSystemSignal(callback func(), messages ...string) {
 	signalNum := 0
 
 	pipe := make(chan os.Signal, 1)
-	signal.Notify(pipe, os.Interrupt, os.Kill)
+	signal.Notify(pipe, DefaultSignals...)
+	defer signal.Stop(pipe)
 
-Loop:
+	done := make(chan struct{})
 	for {
 		select {
-		case _, ok := <-pipe:
-			if !ok {
-				return
-			}
+		case <-done:
+			return
+		case <-pipe:
 			if signalNum == 0 {
 				signalNum++
 
 				fmt.Fprintln(os.Stderr, toInterfaceArray(messages)...)
 
 				go func() {
-					defer close(pipe)
+					defer close(done)
 					callback()
 				}()
-				continue Loop
 			} else {
 				Ln("Forcing exit")
 				os.Exit(1)
 			}
+		}
+	}
+}
+
+// HandleSignals calls handler in a new goroutine for each of the specified signals
+// that is received, until the returned stop function is called; stop unregisters
+// the signals and waits for the goroutine to return, so HandleSignals
+// can be safely used in tests and long-lived libraries.
+// If no signals are specified, nothing is done.
+func HandleSignals(handler func(os.Signal), signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		return func() {}
+	}
+	pipe := make(chan os.Signal, 1)
+	signal.Notify(pipe, signals...)
 
+	quit := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-quit:
+				return
+			case sig := <-pipe:
+				handler(sig)
+			}
 		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(pipe)
+			close(quit)
+			<-exited
+		})
 	}
+}
+
+// OnReload calls reload each time the program receives a SIGHUP,
+// until stop is called; it does nothing on platforms without SIGHUP.
+func OnReload(reload func()) (stop func()) {
+	return HandleSignals(func(os.Signal) { reload() }, ReloadSignals...)
+}
+
+// OnDebugSignal calls fn each time the program receives a SIGUSR1,
+// until stop is called; it does nothing on platforms without SIGUSR1.
+func OnDebugSignal(fn func()) (stop func()) {
+	return HandleSignals(func(os.Signal) { fn() }, DebugSignals...)
+}
 
+// ContextWithSignals returns a copy of parent that is canceled when
+// one of the DefaultSignals is received; stop unregisters the signals.
+func ContextWithSignals(parent context.Context) (ctx context.Context, stop func()) {
+	return signal.NotifyContext(parent, DefaultSignals...)
 }
 
 // WaitHasTimedout waits for the waitgroup for the specified max timeout.
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package utilz
+
+import (
+	"os"
+)
+
+var (
+	// ReloadSignals are the signals handled by OnReload;
+	// there are none on this platform.
+	ReloadSignals []os.Signal
+	// DebugSignals are the signals handled by OnDebugSignal;
+	// there are none on this platform.
+	DebugSignals []os.Signal
+)
@@ -0,0 +1,15 @@
+//go:build unix
+
+package utilz
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	// ReloadSignals are the signals handled by OnReload.
+	ReloadSignals = []os.Signal{syscall.SIGHUP}
+	// DebugSignals are the signals handled by OnDebugSignal.
+	DebugSignals = []os.Signal{syscall.SIGUSR1}
+)
@@ -1,13 +1,12 @@
 module github.com/gagliardetto/utilz
 
-go 1.15
+go 1.21
 
 require (
 	github.com/aybabtme/rgbterm v0.0.0-20170906152045-cc83f3b3ce59
 	github.com/buger/goterm v0.0.0-20200322175922-2f3e71b85129
 	github.com/davecgh/go-spew v1.1.1
 	github.com/gagliardetto/hashsearch v0.0.0-20191005111333-09dd671e19f9
-	github.com/hako/durafmt v0.0.0-20200710122514-c0fb7b4da026
 	github.com/kr/pretty v0.2.1
 	github.com/miekg/dns v1.1.35
 	github.com/ryanuber/go-glob v1.0.0
@@ -15,3 +14,10 @@ require (
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
 	gopkg.in/yaml.v2 v2.4.0
 )
+
+require (
+	github.com/kr/text v0.1.0 // indirect
+	golang.org/x/net v0.0.0-20190923162816-aa69164e4478 // indirect
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
+	golang.org/x/term v0.0.0-20201117132131-f5c789dd3221 // indirect
+)
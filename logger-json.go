@@ -0,0 +1,147 @@
+package utilz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LogEncoding is the format in which a Logger writes its messages.
+type LogEncoding int
+
+const (
+	// LogEncodingText writes messages as `prefix[a|b|c] message [k=v]`.
+	LogEncodingText LogEncoding = iota
+	// LogEncodingJSON writes each message as one JSON object per line.
+	LogEncodingJSON
+)
+
+// Keys of the JSON log objects.
+const (
+	LogKeyLevel   = "level"
+	LogKeyPrefix  = "prefix"
+	LogKeyMessage = "msg"
+	LogKeyTime    = "time"
+	LogKeyElapsed = "elapsed"
+	LogKeyNumber  = "n"
+	LogKeyRunID   = "run_id"
+	LogKeyCaller  = "caller"
+)
+
+// logKeyFieldsPrefix is prepended to the user keys that collide
+// with the LogKey* keys, e.g. "msg" is written as "fields.msg".
+const logKeyFieldsPrefix = "fields."
+
+var logReservedKeys = map[string]bool{
+	LogKeyLevel:   true,
+	LogKeyPrefix:  true,
+	LogKeyMessage: true,
+	LogKeyTime:    true,
+	LogKeyElapsed: true,
+	LogKeyNumber:  true,
+	LogKeyRunID:   true,
+	LogKeyCaller:  true,
+}
+
+// writeJSON writes one JSON object for the message; must be called with l.mu held.
+// NOTE: the header parameters are not used in JSON mode.
+// The time and caller keys are omitted if at is zero or pc is 0.
+func (l *Logger) writeJSON(level LogLevel, msg string, keyvals []interface{}, pc uintptr, at time.Time) {
+	now := time.Now()
+
+	obj := new(jsonObjectBuilder)
+	obj.Add(LogKeyLevel, level.String())
+	obj.Add(LogKeyPrefix, StripANSI(level.Prefix()))
+	obj.Add(LogKeyMessage, StripANSI(msg))
+	if !at.IsZero() {
+		obj.Add(LogKeyTime, at.Format(time.RFC3339Nano))
+	}
+	obj.Add(LogKeyElapsed, formatDuration(now.Sub(l.startedAt)))
+	obj.Add(LogKeyNumber, atomic.LoadInt64(&l.messageCounter))
+	obj.Add(LogKeyRunID, logRunID)
+	if pc != 0 {
+		file, line := getPCLocation(pc)
+		obj.Add(LogKeyCaller, Sf("%s:%v", file, line))
+	}
+
+	for _, kv := range [][]interface{}{l.fields, keyvals} {
+		for i := 0; i < len(kv); i += 2 {
+			var v interface{} = "MISSING"
+			if i+1 < len(kv) {
+				v = kv[i+1]
+			}
+			key := fmt.Sprint(kv[i])
+			if logReservedKeys[key] {
+				key = logKeyFieldsPrefix + key
+			}
+			obj.Add(key, v)
+		}
+	}
+
+	{
+		atomic.AddInt64(&l.messageCounter, 1)
+		atomic.StoreInt64(&l.lastMessageAt, now.UnixNano())
+	}
+
+	l.out.Write(append(obj.Bytes(), '\n'))
+}
+
+// jsonObjectBuilder builds a JSON object keeping the keys
+// in the order in which they were added.
+type jsonObjectBuilder struct {
+	buf bytes.Buffer
+}
+
+func (b *jsonObjectBuilder) Add(key string, val interface{}) {
+	if b.buf.Len() == 0 {
+		b.buf.WriteByte('{')
+	} else {
+		b.buf.WriteByte(',')
+	}
+	b.buf.Write(mustMarshalJSONString(key))
+	b.buf.WriteByte(':')
+	b.buf.Write(marshalJSONValue(val))
+}
+
+func (b *jsonObjectBuilder) Bytes() []byte {
+	if b.buf.Len() == 0 {
+		return []byte("{}")
+	}
+	return append(b.buf.Bytes(), '}')
+}
+
+func mustMarshalJSONString(s string) []byte {
+	b, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// marshalJSONValue marshals v to JSON; errors are rendered as their message,
+// and values that can't be marshaled are rendered with fmt.Sprint.
+func marshalJSONValue(v interface{}) []byte {
+	switch vv := v.(type) {
+	case error:
+		return mustMarshalJSONString(vv.Error())
+	case time.Duration:
+		return mustMarshalJSONString(vv.String())
+	case string:
+		return mustMarshalJSONString(vv)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return mustMarshalJSONString(strings.TrimSpace(fmt.Sprint(v)))
+	}
+	return b
+}
+
+// getPCLocation returns the base filename and line of the program counter.
+func getPCLocation(pc uintptr) (string, int) {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return getBaseFilename(frame.File), frame.Line
+}
@@ -0,0 +1,461 @@
+package utilz
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel is the severity of a log message.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelSuccess
+	LogLevelWarn
+	LogLevelError
+	LogLevelFatal
+)
+
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelSuccess:
+		return "success"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	case LogLevelFatal:
+		return "fatal"
+	default:
+		return "level(" + strconv.Itoa(int(level)) + ")"
+	}
+}
+
+// ParseLogLevel parses a level name (e.g. "info", "WARN") into a LogLevel.
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "debu":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "success", "succ":
+		return LogLevelSuccess, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "error", "erro":
+		return LogLevelError, nil
+	case "fatal":
+		return LogLevelFatal, nil
+	}
+	return LogLevelDebug, fmt.Errorf("unknown log level: %q", s)
+}
+
+// Prefix returns the (colored) prefix that is printed in front of
+// messages of this level.
+func (level LogLevel) Prefix() string {
+	switch level {
+	case LogLevelDebug:
+		return DebugPrefix
+	case LogLevelInfo:
+		return InfoPrefix
+	case LogLevelSuccess:
+		return Lime(SuccessPrefix)
+	case LogLevelWarn:
+		return Yellow(WarnPrefix)
+	case LogLevelError:
+		return RedBG(ErrorPrefix)
+	case LogLevelFatal:
+		return RedBG(FatalPrefix)
+	default:
+		return "[" + strings.ToUpper(level.String()) + "]"
+	}
+}
+
+// Logger writes log messages with the `prefix[a|b|c] message` header format
+// to its own output; messages below its minimum level are discarded.
+type Logger struct {
+	*loggerCore
+
+	// fields are the key/value pairs added with With.
+	fields []interface{}
+}
+
+// loggerCore is the state shared between a logger and
+// all the child loggers created with With.
+type loggerCore struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  LogLevel
+	params []LogHeaderParameter
+
+	startedAt time.Time
+
+	messageCounter       int64
+	lastMessageAt        int64 // UnixNano; accessed atomically
+	useDefaultParameters bool
+
+	encoding LogEncoding
+}
+
+// NewLogger returns a new Logger that writes to out, logs all levels,
+// and has its own message counter and start time.
+func NewLogger(out io.Writer) *Logger {
+	l := newLogger(out, time.Now())
+	l.params = []LogHeaderParameter{
+		l.MessageNumber,
+		LogParamTimestamp,
+		l.ElapsedFromStart,
+	}
+	return l
+}
+
+func newLogger(out io.Writer, startedAt time.Time) *Logger {
+	return &Logger{
+		loggerCore: &loggerCore{
+			mu:        &sync.Mutex{},
+			out:       out,
+			level:     LogLevelDebug,
+			startedAt: startedAt,
+		},
+	}
+}
+
+// defaultLogger is the logger used by the package-level log functions;
+// its header parameters are read from DefaultLogParameters at each call.
+var defaultLogger = func() *Logger {
+	l := newLogger(os.Stderr, logStartedAt)
+	l.useDefaultParameters = true
+	return l
+}()
+
+// DefaultLogger returns the logger used by Debugf, Infof, Warnf, etc.
+func DefaultLogger() *Logger {
+	return defaultLogger
+}
+
+// SetOutput sets the destination of the log messages.
+func (l *Logger) SetOutput(out io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = out
+}
+
+// SetLevel sets the minimum level of the messages that will be written.
+func (l *Logger) SetLevel(level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// Level returns the minimum level of the messages that will be written.
+func (l *Logger) Level() LogLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// Enabled returns true if messages of the provided level will be written.
+func (l *Logger) Enabled(level LogLevel) bool {
+	return level >= l.Level()
+}
+
+// SetParameters sets the parameters that are rendered inside the
+// square brackets of the header of each log message.
+func (l *Logger) SetParameters(params ...LogHeaderParameter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.params = params
+	l.useDefaultParameters = false
+}
+
+// SetEncoding sets the format of the log messages.
+func (l *Logger) SetEncoding(enc LogEncoding) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.encoding = enc
+}
+
+// MessageNumber returns the number of messages written by this logger;
+// it can be used as a LogHeaderParameter.
+func (l *Logger) MessageNumber() string {
+	return strconv.FormatInt(atomic.LoadInt64(&l.messageCounter), 10)
+}
+
+// ElapsedFromStart returns the time passed since the creation of this logger;
+// it can be used as a LogHeaderParameter.
+func (l *Logger) ElapsedFromStart() string {
+	return formatDuration(time.Now().Sub(l.startedAt))
+}
+
+// ElapsedFromLastLogMessage returns the time passed since the last message
+// written by this logger; it can be used as a LogHeaderParameter.
+func (l *Logger) ElapsedFromLastLogMessage() string {
+	return formatDuration(time.Now().Sub(time.Unix(0, atomic.LoadInt64(&l.lastMessageAt))))
+}
+
+// NOTE: all exported log functions and methods must call output directly,
+// so that the caller of the log method is always at the same stack depth.
+func (l *Logger) output(level LogLevel, params []LogHeaderParameter, msg string, keyvals []interface{}) {
+	var pcs [1]uintptr
+	// skip runtime.Callers, output, and the log method:
+	runtime.Callers(3, pcs[:])
+	l.write(level, params, msg, keyvals, pcs[0], time.Now())
+}
+
+// write writes the message; pc is the program counter of the log call
+// (0 if unknown), and at is the time of the message (zero if unknown).
+func (l *Logger) write(level LogLevel, params []LogHeaderParameter, msg string, keyvals []interface{}, pc uintptr, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level < l.level {
+		return
+	}
+	if l.encoding == LogEncodingJSON {
+		l.writeJSON(level, msg, keyvals, pc, at)
+		return
+	}
+
+	header := l.header(params, pc, at)
+	fields := formatFields(append(l.fields[:len(l.fields):len(l.fields)], keyvals...))
+	if !ColorEnabled() {
+		// the level prefixes follow ColorMode, but the message and the fields
+		// might contain colors added by the caller (e.g. with RedBG):
+		header = StripANSI(header)
+		msg = StripANSI(msg)
+		fields = StripANSI(fields)
+	}
+	if fields == "" {
+		fmt.Fprintln(l.out, header, msg)
+	} else {
+		fmt.Fprintln(l.out, header, msg, fields)
+	}
+}
+
+// formatFields renders key/value pairs in the header style: [k1=v1|k2=v2]
+func formatFields(keyvals []interface{}) string {
+	if len(keyvals) == 0 {
+		return ""
+	}
+	keys, vals := ToKeyVals(keyvals...)
+	pairs := make([]string, len(keys))
+	for i := range keys {
+		pairs[i] = keys[i] + "=" + quoteFieldValue(vals[i])
+	}
+	return "[" + strings.Join(pairs, "|") + "]"
+}
+
+// quoteFieldValue quotes values that would be ambiguous
+// when rendered inside a fields block.
+func quoteFieldValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n\"|=[]") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// With returns a child logger that adds the provided key/value pairs to
+// every message; the child shares output, level and counters with its parent.
+// A key without a value gets the MISSING value, so that the pairs of the
+// children and of the log calls stay aligned.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals)+1)
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	if len(keyvals)%2 != 0 {
+		fields = append(fields, "MISSING")
+	}
+	return &Logger{
+		loggerCore: l.loggerCore,
+		fields:     fields,
+	}
+}
+
+// header builds the header of a log message; must be called with l.mu held.
+func (l *Logger) header(params []LogHeaderParameter, pc uintptr, at time.Time) string {
+	var headerPrefix string
+	{
+		var headerVals []string
+		for _, v := range params {
+			if val, ok := headerParamValue(v, pc, at); ok {
+				headerVals = append(headerVals, val)
+			}
+		}
+		headerPrefix = strings.Join(headerVals, "|")
+	}
+
+	loggerParams := l.params
+	if l.useDefaultParameters {
+		loggerParams = DefaultLogParameters
+	}
+	var headerVals []string
+	for _, v := range loggerParams {
+		if val, ok := headerParamValue(v, pc, at); ok {
+			headerVals = append(headerVals, val)
+		}
+	}
+	header := strings.Join(headerVals, "|")
+	header = headerPrefix + "[" + header + "]"
+
+	{
+		atomic.AddInt64(&l.messageCounter, 1)
+		atomic.StoreInt64(&l.lastMessageAt, time.Now().UnixNano())
+	}
+
+	return header
+}
+
+var (
+	logParamCallStackPointer   = reflect.ValueOf(LogParamCallStack).Pointer()
+	logParamTimestampPointer   = reflect.ValueOf(LogParamTimestamp).Pointer()
+	logParamTimestampMsPointer = reflect.ValueOf(LogParamTimestampMs).Pointer()
+)
+
+// headerParamValue returns the value of a header parameter for the message
+// logged at pc and at; the caller and the timestamp are taken from the message
+// instead of the stack depth and the current time, so that they are also right
+// for the records of SlogHandler. If the message has no such value, ok is false.
+func headerParamValue(param LogHeaderParameter, pc uintptr, at time.Time) (val string, ok bool) {
+	switch reflect.ValueOf(param).Pointer() {
+	case logParamCallStackPointer:
+		if pc == 0 {
+			return "", false
+		}
+		file, line := getPCLocation(pc)
+		return Sf("%s:%v", file, line), true
+	case logParamTimestampPointer:
+		if at.IsZero() {
+			return "", false
+		}
+		return at.Format("15:04:05"), true
+	case logParamTimestampMsPointer:
+		if at.IsZero() {
+			return "", false
+		}
+		return at.Format("15:04:05.999"), true
+	}
+	return param(), true
+}
+
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	l.output(
+		LogLevelDebug,
+		newParamsWithLogLevel(LogLevelDebug),
+		fmt.Sprintf(format, a...),
+		nil,
+	)
+}
+func (l *Logger) Infof(format string, a ...interface{}) {
+	l.output(
+		LogLevelInfo,
+		newParamsWithLogLevel(LogLevelInfo),
+		fmt.Sprintf(format, a...),
+		nil,
+	)
+}
+func (l *Logger) Successf(format string, a ...interface{}) {
+	l.output(
+		LogLevelSuccess,
+		newParamsWithLogLevel(LogLevelSuccess),
+		fmt.Sprintf(format, a...),
+		nil,
+	)
+}
+func (l *Logger) Warnf(format string, a ...interface{}) {
+	l.output(
+		LogLevelWarn,
+		newParamsWithLogLevel(LogLevelWarn),
+		fmt.Sprintf(format, a...),
+		nil,
+	)
+}
+func (l *Logger) Errorf(format string, a ...interface{}) {
+	l.output(
+		LogLevelError,
+		newParamsWithLogLevel(LogLevelError),
+		fmt.Sprintf(RedBG(format), a...),
+		nil,
+	)
+}
+
+// Fatalf logs the message and then calls os.Exit(1).
+func (l *Logger) Fatalf(format string, a ...interface{}) {
+	l.output(
+		LogLevelFatal,
+		append(
+			newParamsWithLogLevel(LogLevelFatal),
+			LogParamCallStack,
+		),
+		fmt.Sprintf(RedBG(format), a...),
+		nil,
+	)
+	os.Exit(1)
+}
+
+func (l *Logger) Debugw(msg string, keyvals ...interface{}) {
+	l.output(
+		LogLevelDebug,
+		newParamsWithLogLevel(LogLevelDebug),
+		msg,
+		keyvals,
+	)
+}
+func (l *Logger) Infow(msg string, keyvals ...interface{}) {
+	l.output(
+		LogLevelInfo,
+		newParamsWithLogLevel(LogLevelInfo),
+		msg,
+		keyvals,
+	)
+}
+func (l *Logger) Successw(msg string, keyvals ...interface{}) {
+	l.output(
+		LogLevelSuccess,
+		newParamsWithLogLevel(LogLevelSuccess),
+		msg,
+		keyvals,
+	)
+}
+func (l *Logger) Warnw(msg string, keyvals ...interface{}) {
+	l.output(
+		LogLevelWarn,
+		newParamsWithLogLevel(LogLevelWarn),
+		msg,
+		keyvals,
+	)
+}
+func (l *Logger) Errorw(msg string, keyvals ...interface{}) {
+	l.output(
+		LogLevelError,
+		newParamsWithLogLevel(LogLevelError),
+		RedBG(msg),
+		keyvals,
+	)
+}
+
+// Fatalw logs the message and then calls os.Exit(1).
+func (l *Logger) Fatalw(msg string, keyvals ...interface{}) {
+	l.output(
+		LogLevelFatal,
+		append(
+			newParamsWithLogLevel(LogLevelFatal),
+			LogParamCallStack,
+		),
+		RedBG(msg),
+		keyvals,
+	)
+	os.Exit(1)
+}
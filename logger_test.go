@@ -0,0 +1,102 @@
+package utilz
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Run with -race: the last message time is read while other goroutines log.
+func TestLoggerElapsedFromLastLogMessageConcurrent(t *testing.T) {
+	for _, enc := range []LogEncoding{LogEncodingText, LogEncodingJSON} {
+		l := NewLogger(io.Discard)
+		l.SetEncoding(enc)
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Infof("message %v", j)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.ElapsedFromLastLogMessage()
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestLogLevelPrefixFollowsColorMode(t *testing.T) {
+	defer SetColorMode(GetColorMode())
+
+	SetColorMode(ColorModeNever)
+	if got := LogLevelWarn.Prefix(); got != WarnPrefix {
+		t.Fatalf("got %q with colors disabled", got)
+	}
+	SetColorMode(ColorModeAlways)
+	got := LogLevelWarn.Prefix()
+	if got == WarnPrefix || StripANSI(got) != WarnPrefix {
+		t.Fatalf("got %q with colors enabled", got)
+	}
+}
+
+func TestLoggerJSONReservedKeys(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(buf)
+	l.SetEncoding(LogEncodingJSON)
+	l.With(LogKeyLevel, "user level").Infow("the message", LogKeyMessage, "user message", "other", 1)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	expected := map[string]interface{}{
+		LogKeyLevel:                        "info",
+		LogKeyMessage:                      "the message",
+		logKeyFieldsPrefix + LogKeyLevel:   "user level",
+		logKeyFieldsPrefix + LogKeyMessage: "user message",
+		"other":                            1.0,
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("got %v=%v, expected %v", k, got[k], v)
+		}
+	}
+	// no key was written twice:
+	if n := bytes.Count(buf.Bytes(), []byte(`"msg":`)); n != 1 {
+		t.Errorf("got %v msg keys in %s", n, buf.Bytes())
+	}
+}
+
+func TestLoggerWithOddFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(buf)
+	l.SetParameters()
+	l.With("orphan").With("service", "api").Infow("msg", "host", "h1", "attempt", 2)
+
+	expected := "[orphan=MISSING|service=api|host=h1|attempt=2]"
+	if got := StripANSI(buf.String()); !strings.Contains(got, expected) {
+		t.Fatalf("got %q, expected the fields %q", got, expected)
+	}
+
+	buf.Reset()
+	l.SetEncoding(LogEncodingJSON)
+	l.With("orphan").With("service", "api").Infow("msg", "host", "h1", "attempt", 2)
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	for k, v := range map[string]interface{}{"orphan": "MISSING", "service": "api", "host": "h1", "attempt": 2.0} {
+		if got[k] != v {
+			t.Errorf("got %v=%v, expected %v", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package utilz
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The pipeline stages below stop (and close their output channels) when
+// their input channels are closed or when ctx is done; a stage never blocks
+// forever on a send once ctx is done, so no goroutine is leaked as long as
+// the context is eventually canceled.
+
+// OrDone returns a channel that yields the values of in, and is closed
+// when in is closed or ctx is done.
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !sendContext(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// sendContext sends v on ch, unless ctx is done first;
+// returns false if v was not sent.
+func sendContext[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- v:
+		return true
+	}
+}
+
+// Merge (fan-in) returns a channel that yields the values of all the
+// input channels, and is closed when all of them are closed or ctx is done.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	wg := new(sync.WaitGroup)
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				if !sendContext(ctx, out, v) {
+					return
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// FanOut processes the values of in with fn on the specified number of
+// concurrent workers, and returns a channel that yields the results (in
+// the order in which they are ready).
+func FanOut[T, R any](ctx context.Context, in <-chan T, workers int, fn func(ctx context.Context, v T) R) <-chan R {
+	if workers < 1 {
+		workers = 1
+	}
+	outs := make([]<-chan R, workers)
+	for w := range outs {
+		out := make(chan R)
+		outs[w] = out
+		go func() {
+			defer close(out)
+			for v := range OrDone(ctx, in) {
+				if !sendContext(ctx, out, fn(ctx, v)) {
+					return
+				}
+			}
+		}()
+	}
+	return Merge(ctx, outs...)
+}
+
+// Batch groups the values of in into slices of at most size values;
+// a batch is emitted when it is full, or when maxWait has passed since its
+// first value was received (if maxWait > 0). The last, partial batch is
+// emitted when in is closed.
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	return BatchWithClock(ctx, in, size, maxWait, nil)
+}
+
+// BatchWithClock is like Batch, but uses the provided clock.
+func BatchWithClock[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration, clock Clock) <-chan []T {
+	clock = orRealClock(clock)
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		var batch []T
+		var timer ClockTimer
+		var timeout <-chan time.Time
+		stopTimer := func() {
+			if timer != nil {
+				timer.Stop()
+				timer = nil
+				timeout = nil
+			}
+		}
+		defer stopTimer()
+		flush := func() bool {
+			stopTimer()
+			if len(batch) == 0 {
+				return true
+			}
+			toSend := batch
+			batch = nil
+			return sendContext(ctx, out, toSend)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				timer = nil
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, v)
+				if len(batch) >= size {
+					if !flush() {
+						return
+					}
+				} else if len(batch) == 1 && maxWait > 0 {
+					timer = clock.NewTimer(maxWait)
+					timeout = timer.C()
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle returns a channel that yields the values of in,
+// at most one every interval.
+func Throttle[T any](ctx context.Context, in <-chan T, interval time.Duration) <-chan T {
+	return ThrottleWithClock(ctx, in, interval, nil)
+}
+
+// ThrottleWithClock is like Throttle, but uses the provided clock.
+func ThrottleWithClock[T any](ctx context.Context, in <-chan T, interval time.Duration, clock Clock) <-chan T {
+	clock = orRealClock(clock)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var last time.Time
+		for v := range OrDone(ctx, in) {
+			if !last.IsZero() {
+				if err := sleepContext(ctx, clock, interval-clock.Now().Sub(last)); err != nil {
+					return
+				}
+			}
+			last = clock.Now()
+			if !sendContext(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Tee returns two channels that both yield all the values of in;
+// each value is sent on both channels before the next one is received,
+// so both channels must be consumed.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1 := make(chan T)
+	out2 := make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			// send to both, in whichever order they are ready:
+			o1, o2 := out1, out2
+			for i := 0; i < 2; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Generate returns a channel that yields the provided values,
+// and is closed after the last one or when ctx is done.
+func Generate[T any](ctx context.Context, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			if !sendContext(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
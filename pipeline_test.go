@@ -0,0 +1,208 @@
+package utilz
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkNoGoroutineLeak fails the test if the number of goroutines does not
+// go back to base within a second.
+func checkNoGoroutineLeak(t *testing.T, base int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= base {
+			return
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			buf = buf[:runtime.Stack(buf, true)]
+			t.Fatalf("leaked %v goroutines:\n%s", n-base, buf)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// infiniteSource returns a channel that yields 0, 1, 2, ... until ctx is done.
+func infiniteSource(ctx context.Context) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := 0; ; i++ {
+			if !sendContext(ctx, out, i) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// readThenCancel reads n values from ch, cancels the context,
+// and drains ch until it is closed.
+func readThenCancel[T any](t *testing.T, ch <-chan T, n int, cancel func()) []T {
+	t.Helper()
+	var values []T
+	for i := 0; i < n; i++ {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed after %v values", i)
+			}
+			values = append(values, v)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for value %v", i)
+		}
+	}
+	cancel()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range ch {
+		}
+	}()
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+	return values
+}
+
+func TestOrDoneCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	values := readThenCancel(t, OrDone(ctx, infiniteSource(ctx)), 3, cancel)
+	for i, v := range values {
+		if v != i {
+			t.Errorf("got %v at %v", v, i)
+		}
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestOrDoneClosedInput(t *testing.T) {
+	base := runtime.NumGoroutine()
+	var got []int
+	for v := range OrDone(context.Background(), Generate(context.Background(), 1, 2, 3)) {
+		got = append(got, v)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %v", got)
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestMergeCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	merged := Merge(ctx, infiniteSource(ctx), infiniteSource(ctx), infiniteSource(ctx))
+	readThenCancel(t, merged, 10, cancel)
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestMergeAll(t *testing.T) {
+	ctx := context.Background()
+	sum := 0
+	for v := range Merge(ctx, Generate(ctx, 1, 2), Generate(ctx, 3), Generate[int](ctx)) {
+		sum += v
+	}
+	if sum != 6 {
+		t.Fatalf("got sum %v", sum)
+	}
+}
+
+func TestFanOutCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := FanOut(ctx, infiniteSource(ctx), 4, func(ctx context.Context, v int) int {
+		return v * 2
+	})
+	for _, v := range readThenCancel(t, out, 10, cancel) {
+		if v%2 != 0 {
+			t.Errorf("got odd value %v", v)
+		}
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestBatchCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, batch := range readThenCancel(t, Batch(ctx, infiniteSource(ctx), 5, time.Hour), 3, cancel) {
+		if len(batch) != 5 {
+			t.Errorf("got batch of %v", len(batch))
+		}
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestBatchMaxWait(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := BatchWithClock(ctx, in, 10, time.Second, clock)
+
+	in <- 1
+	in <- 2
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if batch := <-out; len(batch) != 2 {
+		t.Fatalf("got %v", batch)
+	}
+	// cancel while a partial batch is pending:
+	in <- 3
+	cancel()
+	for range out {
+	}
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestThrottleCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	out := ThrottleWithClock(ctx, infiniteSource(ctx), time.Minute, clock)
+
+	if v := <-out; v != 0 {
+		t.Fatalf("got %v", v)
+	}
+	// the second value must wait for the interval:
+	clock.BlockUntil(1)
+	select {
+	case v := <-out:
+		t.Fatalf("got %v before the interval", v)
+	default:
+	}
+	clock.Advance(time.Minute)
+	if v := <-out; v != 1 {
+		t.Fatalf("got %v", v)
+	}
+	// cancel while throttling:
+	clock.BlockUntil(1)
+	readThenCancel(t, out, 0, cancel)
+	checkNoGoroutineLeak(t, base)
+}
+
+func TestTeeCancel(t *testing.T) {
+	base := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	out1, out2 := Tee(ctx, infiniteSource(ctx))
+	for i := 0; i < 3; i++ {
+		v1, v2 := <-out1, <-out2
+		if v1 != i || v2 != i {
+			t.Fatalf("got %v and %v, expected %v", v1, v2, i)
+		}
+	}
+	// only one of the outputs is read; cancel must unblock the other.
+	<-out1
+	cancel()
+	for range out1 {
+	}
+	for range out2 {
+	}
+	checkNoGoroutineLeak(t, base)
+}
@@ -0,0 +1,377 @@
+package utilz
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimiter controls how frequently events are allowed to happen.
+type RateLimiter interface {
+	// Allow reports whether an event may happen now; if so, the event is counted.
+	Allow() bool
+	// Reserve reserves an event, and returns when it may happen.
+	Reserve() *Reservation
+	// Wait blocks until an event may happen, or ctx is done.
+	Wait(ctx context.Context) error
+}
+
+// ErrRateLimitExceeded is returned by Wait when the event could never
+// happen, or would happen after the deadline of the context.
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+// Reservation is an event reserved on a RateLimiter.
+type Reservation struct {
+	ok        bool
+	timeToAct time.Time
+	clock     Clock
+	cancel    func()
+	once      sync.Once
+}
+
+// OK returns false if the event can never happen (e.g. zero burst or limit).
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// TimeToAct returns the time at which the reserved event may happen.
+func (r *Reservation) TimeToAct() time.Time {
+	return r.timeToAct
+}
+
+// Delay returns how long to wait before the reserved event may happen.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	d := r.timeToAct.Sub(r.clock.Now())
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Cancel gives back the reserved event to the limiter,
+// e.g. because the caller will not act on it after all.
+func (r *Reservation) Cancel() {
+	if !r.ok || r.cancel == nil {
+		return
+	}
+	r.once.Do(r.cancel)
+}
+
+// waitReservation waits for the reservation; if ctx is done first,
+// or its deadline is before the time to act, the reservation is canceled.
+func waitReservation(ctx context.Context, r *Reservation) error {
+	if !r.ok {
+		return ErrRateLimitExceeded
+	}
+	if err := ctx.Err(); err != nil {
+		r.Cancel()
+		return err
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	// context deadlines are wall-clock times; compare durations so that
+	// a non-real clock does not make every deadline look far away.
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return ErrRateLimitExceeded
+	}
+	select {
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	case <-r.clock.After(delay):
+		return nil
+	}
+}
+
+// TokenBucket is a RateLimiter that refills a bucket of burst tokens at a
+// fixed rate; each event consumes one token. It is safe for concurrent use.
+type TokenBucket struct {
+	mu     *sync.Mutex
+	clock  Clock
+	rate   float64 // tokens per second
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+var _ RateLimiter = &TokenBucket{}
+
+// NewTokenBucket returns a full token bucket that allows ratePerSecond
+// events per second, with bursts of at most burst events.
+func NewTokenBucket(ratePerSecond float64, burst int) *TokenBucket {
+	return NewTokenBucketWithClock(ratePerSecond, burst, nil)
+}
+
+// NewTokenBucketWithClock is like NewTokenBucket, but uses the provided clock.
+func NewTokenBucketWithClock(ratePerSecond float64, burst int, clock Clock) *TokenBucket {
+	clock = orRealClock(clock)
+	return &TokenBucket{
+		mu:     &sync.Mutex{},
+		clock:  clock,
+		rate:   ratePerSecond,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// Tokens returns the number of available tokens (negative if
+// there are outstanding reservations).
+func (tb *TokenBucket) Tokens() float64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	return tb.tokens
+}
+
+// refill adds the tokens accumulated since the last refill; must be called with tb.mu held.
+func (tb *TokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(tb.last)
+	if elapsed <= 0 {
+		return
+	}
+	tb.last = now
+	tb.tokens = math.Min(float64(tb.burst), tb.tokens+elapsed.Seconds()*tb.rate)
+}
+
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true
+	}
+	return false
+}
+
+func (tb *TokenBucket) Reserve() *Reservation {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.clock.Now()
+	res := &Reservation{clock: tb.clock, timeToAct: now}
+	if tb.burst < 1 || (tb.rate <= 0 && tb.tokens < 1) {
+		return res
+	}
+	tb.refill(now)
+	tb.tokens--
+	if tb.tokens < 0 {
+		wait := time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+		res.timeToAct = now.Add(wait)
+	}
+	res.ok = true
+	res.cancel = func() {
+		tb.mu.Lock()
+		defer tb.mu.Unlock()
+		tb.refill(tb.clock.Now())
+		tb.tokens = math.Min(float64(tb.burst), tb.tokens+1)
+	}
+	return res
+}
+
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	return waitReservation(ctx, tb.Reserve())
+}
+
+// SlidingWindow is a RateLimiter that allows at most limit events in
+// any time window of the specified size. It is safe for concurrent use.
+type SlidingWindow struct {
+	mu     *sync.Mutex
+	clock  Clock
+	limit  int
+	window time.Duration
+	// events are the (sorted) times of the events in the current window,
+	// including the reserved future ones.
+	events []time.Time
+}
+
+var _ RateLimiter = &SlidingWindow{}
+
+// NewSlidingWindow returns a limiter that allows at most limit events in any window.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return NewSlidingWindowWithClock(limit, window, nil)
+}
+
+// NewSlidingWindowWithClock is like NewSlidingWindow, but uses the provided clock.
+func NewSlidingWindowWithClock(limit int, window time.Duration, clock Clock) *SlidingWindow {
+	return &SlidingWindow{
+		mu:     &sync.Mutex{},
+		clock:  orRealClock(clock),
+		limit:  limit,
+		window: window,
+	}
+}
+
+// prune removes the events that are out of the window; must be called with sw.mu held.
+func (sw *SlidingWindow) prune(now time.Time) {
+	windowStart := now.Add(-sw.window)
+	i := 0
+	for i < len(sw.events) && !sw.events[i].After(windowStart) {
+		i++
+	}
+	sw.events = sw.events[i:]
+}
+
+// Count returns the number of events in the current window
+// (including the reserved future ones).
+func (sw *SlidingWindow) Count() int {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.prune(sw.clock.Now())
+	return len(sw.events)
+}
+
+func (sw *SlidingWindow) Allow() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	now := sw.clock.Now()
+	sw.prune(now)
+	if len(sw.events) < sw.limit {
+		sw.events = append(sw.events, now)
+		return true
+	}
+	return false
+}
+
+func (sw *SlidingWindow) Reserve() *Reservation {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := sw.clock.Now()
+	res := &Reservation{clock: sw.clock, timeToAct: now}
+	if sw.limit < 1 {
+		return res
+	}
+	sw.prune(now)
+	if len(sw.events) >= sw.limit {
+		// the event can happen when the limit-th most recent event leaves the window:
+		res.timeToAct = sw.events[len(sw.events)-sw.limit].Add(sw.window)
+	}
+	sw.events = append(sw.events, res.timeToAct)
+
+	res.ok = true
+	res.cancel = func() {
+		sw.mu.Lock()
+		defer sw.mu.Unlock()
+		// remove the reserved event (if it is still in the window):
+		i := sort.Search(len(sw.events), func(i int) bool {
+			return !sw.events[i].Before(res.timeToAct)
+		})
+		if i < len(sw.events) && sw.events[i].Equal(res.timeToAct) {
+			sw.events = append(sw.events[:i], sw.events[i+1:]...)
+		}
+	}
+	return res
+}
+
+func (sw *SlidingWindow) Wait(ctx context.Context) error {
+	return waitReservation(ctx, sw.Reserve())
+}
+
+// KeyedLimiter keeps a separate RateLimiter for each key (e.g. a hostname);
+// limiters that have not been used for the idle timeout are evicted.
+// It is safe for concurrent use.
+type KeyedLimiter struct {
+	mu          *sync.Mutex
+	clock       Clock
+	newLimiter  func(key string) RateLimiter
+	idleTimeout time.Duration
+	limiters    map[string]*keyedLimiterEntry
+	lastSweep   time.Time
+}
+
+type keyedLimiterEntry struct {
+	limiter  RateLimiter
+	lastUsed time.Time
+}
+
+// NewKeyedLimiter returns a KeyedLimiter that creates the limiter of each key
+// with newLimiter; if idleTimeout > 0, the limiters that have not been used
+// for idleTimeout are evicted (and will be created again if needed).
+// The idle timeout should be longer than the time the limiters
+// need to recover from a burst.
+func NewKeyedLimiter(newLimiter func(key string) RateLimiter, idleTimeout time.Duration) *KeyedLimiter {
+	return NewKeyedLimiterWithClock(newLimiter, idleTimeout, nil)
+}
+
+// NewKeyedLimiterWithClock is like NewKeyedLimiter, but uses the provided clock
+// to track the idle time.
+func NewKeyedLimiterWithClock(newLimiter func(key string) RateLimiter, idleTimeout time.Duration, clock Clock) *KeyedLimiter {
+	clock = orRealClock(clock)
+	return &KeyedLimiter{
+		mu:          &sync.Mutex{},
+		clock:       clock,
+		newLimiter:  newLimiter,
+		idleTimeout: idleTimeout,
+		limiters:    make(map[string]*keyedLimiterEntry),
+		lastSweep:   clock.Now(),
+	}
+}
+
+// Get returns the limiter of the key, creating it if needed.
+func (kl *KeyedLimiter) Get(key string) RateLimiter {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	now := kl.clock.Now()
+	if kl.idleTimeout > 0 && now.Sub(kl.lastSweep) >= kl.idleTimeout {
+		kl.evictIdle(now)
+	}
+
+	entry, ok := kl.limiters[key]
+	if !ok {
+		entry = &keyedLimiterEntry{limiter: kl.newLimiter(key)}
+		kl.limiters[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// evictIdle removes the idle limiters; must be called with kl.mu held.
+func (kl *KeyedLimiter) evictIdle(now time.Time) {
+	kl.lastSweep = now
+	for key, entry := range kl.limiters {
+		if now.Sub(entry.lastUsed) >= kl.idleTimeout {
+			delete(kl.limiters, key)
+		}
+	}
+}
+
+// EvictIdle removes the limiters that have not been used for the idle timeout.
+func (kl *KeyedLimiter) EvictIdle() {
+	if kl.idleTimeout <= 0 {
+		return
+	}
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	kl.evictIdle(kl.clock.Now())
+}
+
+// Len returns the number of keys that have a limiter.
+func (kl *KeyedLimiter) Len() int {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	return len(kl.limiters)
+}
+
+func (kl *KeyedLimiter) Allow(key string) bool {
+	return kl.Get(key).Allow()
+}
+
+func (kl *KeyedLimiter) Reserve(key string) *Reservation {
+	return kl.Get(key).Reserve()
+}
+
+func (kl *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return kl.Get(key).Wait(ctx)
+}
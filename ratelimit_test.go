@@ -0,0 +1,188 @@
+package utilz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	clock := newTestClock()
+	tb := NewTokenBucketWithClock(2, 3, clock)
+
+	for i := 0; i < 3; i++ {
+		if !tb.Allow() {
+			t.Fatalf("event %v of the burst not allowed", i)
+		}
+	}
+	if tb.Allow() {
+		t.Fatal("allowed more than the burst")
+	}
+	// 2 tokens per second: one token every 500ms.
+	clock.Advance(499 * time.Millisecond)
+	if tb.Allow() {
+		t.Fatal("allowed before the refill")
+	}
+	clock.Advance(time.Millisecond)
+	if !tb.Allow() {
+		t.Fatal("not allowed after the refill")
+	}
+	// the bucket never holds more than the burst:
+	clock.Advance(time.Hour)
+	if got := tb.Tokens(); got != 3 {
+		t.Fatalf("got %v tokens, expected 3", got)
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	clock := newTestClock()
+	tb := NewTokenBucketWithClock(1, 1, clock)
+
+	if r := tb.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("first reservation: ok=%v delay=%v", r.OK(), r.Delay())
+	}
+	r := tb.Reserve()
+	if !r.OK() || r.Delay() != time.Second {
+		t.Fatalf("second reservation: ok=%v delay=%v", r.OK(), r.Delay())
+	}
+	// canceling gives the token back:
+	r.Cancel()
+	r.Cancel() // must be idempotent
+	if r := tb.Reserve(); r.Delay() != time.Second {
+		t.Fatalf("reservation after cancel: delay=%v", r.Delay())
+	}
+
+	if r := NewTokenBucketWithClock(1, 0, clock).Reserve(); r.OK() {
+		t.Fatal("a zero burst must never allow events")
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clock := newTestClock()
+	tb := NewTokenBucketWithClock(1, 1, clock)
+	tb.Allow()
+
+	done := make(chan error)
+	go func() {
+		done <- tb.Wait(context.Background())
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// a deadline before the time to act fails right away,
+	// and gives back the reservation:
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := tb.Wait(ctx); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("got %v", err)
+	}
+	if got := tb.Tokens(); got != 0 {
+		t.Fatalf("got %v tokens, expected 0", got)
+	}
+}
+
+func TestTokenBucketWaitCancel(t *testing.T) {
+	clock := newTestClock()
+	tb := NewTokenBucketWithClock(1, 1, clock)
+	tb.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- tb.Wait(ctx)
+	}()
+	clock.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v", err)
+	}
+	// the canceled reservation was given back:
+	clock.Advance(time.Second)
+	if !tb.Allow() {
+		t.Fatal("token not given back")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := newTestClock()
+	sw := NewSlidingWindowWithClock(3, time.Minute, clock)
+
+	for i := 0; i < 3; i++ {
+		if !sw.Allow() {
+			t.Fatalf("event %v not allowed", i)
+		}
+		clock.Advance(10 * time.Second)
+	}
+	if sw.Allow() {
+		t.Fatal("allowed more than the limit")
+	}
+	// the first event leaves the window 60s after it happened:
+	r := sw.Reserve()
+	if !r.OK() || r.Delay() != 30*time.Second {
+		t.Fatalf("ok=%v delay=%v", r.OK(), r.Delay())
+	}
+	r.Cancel()
+	if got := sw.Count(); got != 3 {
+		t.Fatalf("got count %v, expected 3", got)
+	}
+	clock.Advance(30 * time.Second)
+	if got := sw.Count(); got != 2 {
+		t.Fatalf("got count %v, expected 2", got)
+	}
+	if !sw.Allow() {
+		t.Fatal("not allowed after the window moved")
+	}
+
+	if r := NewSlidingWindowWithClock(0, time.Minute, clock).Reserve(); r.OK() {
+		t.Fatal("a zero limit must never allow events")
+	}
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	clock := newTestClock()
+	created := 0
+	kl := NewKeyedLimiterWithClock(func(key string) RateLimiter {
+		created++
+		return NewTokenBucketWithClock(1, 1, clock)
+	}, time.Minute, clock)
+
+	if !kl.Allow("a") || !kl.Allow("b") {
+		t.Fatal("first events not allowed")
+	}
+	if kl.Allow("a") {
+		t.Fatal("the limiters of the keys are not separate")
+	}
+	if kl.Len() != 2 || created != 2 {
+		t.Fatalf("got %v limiters, %v created", kl.Len(), created)
+	}
+
+	// "a" stays in use, "b" becomes idle:
+	clock.Advance(50 * time.Second)
+	kl.Allow("a")
+	clock.Advance(20 * time.Second)
+	kl.EvictIdle()
+	if kl.Len() != 1 {
+		t.Fatalf("got %v limiters after eviction, expected 1", kl.Len())
+	}
+	kl.Allow("b")
+	if created != 3 {
+		t.Fatalf("got %v limiters created, expected 3", created)
+	}
+}
+
+func ExampleTokenBucket() {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewTokenBucketWithClock(1, 2, clock)
+	for i := 0; i < 3; i++ {
+		fmt.Println(limiter.Allow())
+	}
+	// Output:
+	// true
+	// true
+	// false
+}
@@ -0,0 +1,138 @@
+package utilz
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryAfterError is implemented by the errors that carry a hint of
+// when the operation should be retried (e.g. from a Retry-After header);
+// the retry engine waits for the hinted delay instead of its computed
+// one, bounded by RetryPolicy.MaxDelay. A zero hint means "retry now",
+// and a negative one means that there is no hint.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+func (e *retryAfterError) RetryAfter() time.Duration {
+	return e.after
+}
+
+// WithRetryAfter wraps err with the hint that the operation should be retried after d.
+func WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, after: d}
+}
+
+// GetRetryAfter returns the retry hint of err (or of any error it wraps).
+func GetRetryAfter(err error) (time.Duration, bool) {
+	var hinted RetryAfterError
+	if errors.As(err, &hinted) {
+		if after := hinted.RetryAfter(); after >= 0 {
+			return after, true
+		}
+	}
+	return 0, false
+}
+
+// RetryAfterFromResponse parses the Retry-After header of the response,
+// which can be either a number of seconds or an HTTP date.
+func RetryAfterFromResponse(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	return ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which can be either
+// a number of seconds or an HTTP date; dates are relative to now.
+// Values too large for a time.Duration are clamped to the maximum duration.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil || (errors.Is(err, strconv.ErrRange) && seconds > 0) {
+		if seconds < 0 {
+			return 0, false
+		}
+		// clamp the values that would overflow a time.Duration.
+		if seconds > int64(math.MaxInt64/time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	d := date.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// HTTPStatusError is the error for an HTTP response with an unexpected status;
+// it implements RetryAfterError with the Retry-After header of the response.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+	// After is the value of the Retry-After header, if HasRetryAfter is true.
+	After         time.Duration
+	HasRetryAfter bool
+}
+
+// NewHTTPStatusError returns an error that describes the status of the
+// response; resp can be nil.
+func NewHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	if resp == nil {
+		return &HTTPStatusError{}
+	}
+	after, ok := RetryAfterFromResponse(resp)
+	return &HTTPStatusError{
+		StatusCode:    resp.StatusCode,
+		Status:        resp.Status,
+		After:         after,
+		HasRetryAfter: ok,
+	}
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.StatusCode == 0 && e.Status == "" {
+		return "no HTTP response"
+	}
+	if e.Status != "" {
+		return Sf("unexpected HTTP status: %s", e.Status)
+	}
+	return Sf("unexpected HTTP status: %v", e.StatusCode)
+}
+
+// RetryAfter returns the value of the Retry-After header,
+// or -1 if the response did not have one.
+func (e *HTTPStatusError) RetryAfter() time.Duration {
+	if !e.HasRetryAfter {
+		return -1
+	}
+	return e.After
+}
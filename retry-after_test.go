@@ -0,0 +1,172 @@
+package utilz
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"0", 0, true},
+		{"9223372036", 9223372036 * time.Second, true},
+		// the values that would overflow are clamped:
+		{"9223372037", math.MaxInt64, true},
+		{"99999999999", math.MaxInt64, true},
+		{"9223372036854775807", math.MaxInt64, true},
+		{"99999999999999999999", math.MaxInt64, true},
+		{"-99999999999999999999", 0, false},
+		{"120", 2 * time.Minute, true},
+		{" 5 ", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"", 0, false},
+		{"soon", 0, false},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		// a date in the past means "retry now":
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+	}
+	for _, c := range cases {
+		got, ok := ParseRetryAfter(c.value, now)
+		if got != c.expected || ok != c.ok {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v; expected %v, %v", c.value, got, ok, c.expected, c.ok)
+		}
+	}
+}
+
+func TestNewHTTPStatusErrorNilResponse(t *testing.T) {
+	err := NewHTTPStatusError(nil)
+	if err.Error() != "no HTTP response" {
+		t.Fatalf("got %q", err.Error())
+	}
+	if _, ok := GetRetryAfter(err); ok {
+		t.Fatal("a nil response must not have a retry hint")
+	}
+}
+
+// statusSequenceServer returns a server that responds with the provided
+// statuses and Retry-After headers (empty for none), one per request.
+func statusSequenceServer(t *testing.T, statuses []int, retryAfters []string) *httptest.Server {
+	mu := &sync.Mutex{}
+	i := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if i >= len(statuses) {
+			t.Errorf("unexpected request %v", i)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if retryAfters[i] != "" {
+			w.Header().Set("Retry-After", retryAfters[i])
+		}
+		w.WriteHeader(statuses[i])
+		i++
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getStatus(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Permanent(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return NewHTTPStatusError(resp)
+	}
+	return nil
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	server := statusSequenceServer(t,
+		[]int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK},
+		[]string{"0", "2", "", ""},
+	)
+	clock := newTestClock()
+	var delays []time.Duration
+	policy := RetryPolicy{
+		InitialDelay: time.Hour,
+		Multiplier:   1,
+		Clock:        clock,
+		OnRetry: func(attempt int, err error, nextDelay time.Duration) {
+			delays = append(delays, nextDelay)
+		},
+	}
+
+	done := make(chan *RetryResult)
+	go func() {
+		done <- RetryWithResult(context.Background(), policy, func(ctx context.Context, _ RetryInfo) error {
+			return getStatus(ctx, server.URL)
+		})
+	}()
+	// Retry-After: 2
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	// no Retry-After: the computed delay is used.
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+
+	res := <-done
+	if !res.Succeeded() || len(res.Attempts) != 4 {
+		t.Fatalf("got %+v", res)
+	}
+	var statusErr *HTTPStatusError
+	if !errors.As(res.Attempts[1].Err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %v", res.Attempts[1].Err)
+	}
+	expected := []time.Duration{0, 2 * time.Second, time.Hour}
+	if len(delays) != len(expected) {
+		t.Fatalf("got delays %v, expected %v", delays, expected)
+	}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Fatalf("got delays %v, expected %v", delays, expected)
+		}
+	}
+}
+
+func TestRetryAfterIsCappedByMaxDelay(t *testing.T) {
+	server := statusSequenceServer(t,
+		[]int{http.StatusTooManyRequests, http.StatusOK},
+		[]string{"3600", ""},
+	)
+	clock := newTestClock()
+	var delay time.Duration
+	policy := RetryPolicy{
+		InitialDelay: time.Millisecond,
+		MaxDelay:     time.Second,
+		Clock:        clock,
+		OnRetry: func(attempt int, err error, nextDelay time.Duration) {
+			delay = nextDelay
+		},
+	}
+	done := make(chan *RetryResult)
+	go func() {
+		done <- RetryWithResult(context.Background(), policy, func(ctx context.Context, _ RetryInfo) error {
+			return getStatus(ctx, server.URL)
+		})
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if res := <-done; !res.Succeeded() || len(res.Attempts) != 2 {
+		t.Fatalf("got %+v", res)
+	}
+	if delay != time.Second {
+		t.Fatalf("got delay %v, expected 1s", delay)
+	}
+}
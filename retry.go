@@ -0,0 +1,345 @@
+package utilz
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Jitter is the strategy used to randomize the retry delays,
+// so that many clients don't retry in lockstep.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type Jitter int
+
+const (
+	// JitterNone uses the computed delay as-is.
+	JitterNone Jitter = iota
+	// JitterFull uses a random delay between 0 and the computed delay.
+	JitterFull
+	// JitterEqual uses half of the computed delay plus a random delay
+	// between 0 and the other half.
+	JitterEqual
+	// JitterDecorrelated uses a random delay between InitialDelay and
+	// three times the previous delay.
+	JitterDecorrelated
+)
+
+// RetryPolicy describes how a task is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of executions of the task (the first
+	// one included); zero means no limit.
+	MaxAttempts int
+	// MaxElapsedTime is the maximum time spent retrying; no retry is attempted
+	// if the next delay would exceed it. Zero means no limit.
+	MaxElapsedTime time.Duration
+
+	// InitialDelay is the delay after the first failed attempt.
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between attempts; zero means no cap.
+	MaxDelay time.Duration
+	// Multiplier is the factor by which the delay grows after each
+	// failed attempt; values lower than 1 are treated as 1 (constant delay).
+	Multiplier float64
+	// Jitter is the randomization strategy of the delays.
+	Jitter Jitter
+
+	// RetryIf, if not nil, is called for each error returned by the task;
+	// if it returns false, the error is considered permanent and no more
+	// attempts are made. Errors wrapped with Permanent are never retried.
+	RetryIf func(err error) bool
+
+	// OnRetry, if not nil, is called after each failed attempt that
+	// will be retried, with the (1-based) attempt number, its error,
+	// and the delay before the next attempt.
+	OnRetry func(attempt int, err error, nextDelay time.Duration)
+	// OnGiveUp, if not nil, is called when the task has failed
+	// and no more attempts will be made.
+	OnGiveUp func(result *RetryResult)
+
+	// Clock is the source of time of the delays (RealClock if nil).
+	Clock Clock
+}
+
+// RetryInfo is passed to the task at each attempt.
+type RetryInfo struct {
+	// Attempt is the 1-based number of the current attempt.
+	Attempt int
+	// Elapsed is the time passed since the first attempt started.
+	Elapsed time.Duration
+}
+
+// RetryAttempt describes one execution of the task.
+type RetryAttempt struct {
+	Attempt   int
+	StartedAt time.Time
+	Duration  time.Duration
+	// Err is the error returned by the task (nil if it succeeded).
+	Err error
+	// Delay is the time waited after this attempt (zero for the last attempt).
+	Delay time.Duration
+}
+
+// RetryResult describes all the attempts of a retried task.
+type RetryResult struct {
+	Attempts []RetryAttempt
+	// Elapsed is the total time spent, delays included.
+	Elapsed time.Duration
+	// Err is nil if the task succeeded; otherwise it is the error of the
+	// last attempt, or the error of the context if it was done.
+	Err error
+
+	// ctxErr is the error of the context, if it was done.
+	ctxErr error
+}
+
+// Succeeded returns true if the last attempt of the task succeeded.
+func (res *RetryResult) Succeeded() bool {
+	return res.Err == nil
+}
+
+// Errors returns the errors of the failed attempts, annotated with the attempt
+// number and duration (see RetryAttemptError), and the error of the context if
+// it was done; the returned slice can be rendered with FormatErrorArray.
+func (res *RetryResult) Errors() []error {
+	var errs []error
+	for _, attempt := range res.Attempts {
+		if attempt.Err != nil {
+			errs = append(errs, &RetryAttemptError{
+				Attempt:  attempt.Attempt,
+				Duration: attempt.Duration,
+				Err:      attempt.Err,
+			})
+		}
+	}
+	if res.ctxErr != nil {
+		errs = append(errs, res.ctxErr)
+	}
+	return errs
+}
+
+// rawErrors returns the errors like Errors does, but without annotations.
+func (res *RetryResult) rawErrors() []error {
+	if res.Err == nil {
+		return nil
+	}
+	var errs []error
+	for _, attempt := range res.Attempts {
+		if attempt.Err != nil {
+			errs = append(errs, attempt.Err)
+		}
+	}
+	if res.ctxErr != nil {
+		errs = append(errs, res.ctxErr)
+	}
+	return errs
+}
+
+// Format renders the failed attempts like FormatErrorArray does,
+// followed by the total elapsed time.
+func (res *RetryResult) Format(prefix string) string {
+	return FormatErrorArray(prefix, res.Errors()) + Sf("\n%stotal: %v attempts in %s", prefix, len(res.Attempts), formatDuration(res.Elapsed))
+}
+
+// RetryAttemptError is the error of a failed attempt.
+type RetryAttemptError struct {
+	Attempt  int
+	Duration time.Duration
+	Err      error
+}
+
+func (e *RetryAttemptError) Error() string {
+	return Sf("attempt %v failed after %s: %s", e.Attempt, formatDuration(e.Duration), e.Err)
+}
+
+func (e *RetryAttemptError) Unwrap() error {
+	return e.Err
+}
+
+// permanentError marks an error that should not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err to signal to Retry that the task must not be retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent returns true if err (or any error it wraps) was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// unwrapPermanent removes the Permanent wrapper, if err is one.
+func unwrapPermanent(err error) error {
+	if perm, ok := err.(*permanentError); ok {
+		return perm.err
+	}
+	return err
+}
+
+// Retry executes task until it succeeds, returns a permanent error,
+// the policy gives up, or ctx is done; no delay follows the last attempt.
+// Returns nil if the task succeeded; otherwise returns the errors of all
+// the attempts (and, if ctx is done, the error of the context as last element).
+func Retry(ctx context.Context, policy RetryPolicy, task func(ctx context.Context) error) []error {
+	res := RetryWithResult(ctx, policy, func(ctx context.Context, _ RetryInfo) error {
+		return task(ctx)
+	})
+	return res.rawErrors()
+}
+
+// RetryWithResult is like Retry, but passes the attempt number and the
+// elapsed time to the task, and returns the details of all the attempts.
+func RetryWithResult(ctx context.Context, policy RetryPolicy, task func(ctx context.Context, info RetryInfo) error) *RetryResult {
+	clock := orRealClock(policy.Clock)
+	res := new(RetryResult)
+	startedAt := clock.Now()
+	var delay time.Duration
+
+	giveUp := func(err error) *RetryResult {
+		res.Err = err
+		res.Elapsed = clock.Now().Sub(startedAt)
+		if policy.OnGiveUp != nil {
+			policy.OnGiveUp(res)
+		}
+		return res
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			res.ctxErr = err
+			return giveUp(err)
+		}
+
+		attemptStartedAt := clock.Now()
+		err := task(ctx, RetryInfo{
+			Attempt: attempt,
+			Elapsed: attemptStartedAt.Sub(startedAt),
+		})
+		res.Attempts = append(res.Attempts, RetryAttempt{
+			Attempt:   attempt,
+			StartedAt: attemptStartedAt,
+			Duration:  clock.Now().Sub(attemptStartedAt),
+			Err:       unwrapPermanent(err),
+		})
+		if err == nil {
+			res.Elapsed = clock.Now().Sub(startedAt)
+			return res
+		}
+		isPermanent := IsPermanent(err)
+		err = unwrapPermanent(err)
+
+		if isPermanent || (policy.RetryIf != nil && !policy.RetryIf(err)) {
+			return giveUp(err)
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return giveUp(err)
+		}
+
+		delay = policy.nextDelay(attempt, delay)
+		if hint, ok := GetRetryAfter(err); ok {
+			// the server knows better (a zero hint means "retry now"):
+			delay = hint
+			if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+				delay = policy.MaxDelay
+			}
+		}
+		if policy.MaxElapsedTime > 0 && delay > policy.MaxElapsedTime-clock.Now().Sub(startedAt) {
+			return giveUp(err)
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+		res.Attempts[len(res.Attempts)-1].Delay = delay
+		if ctxErr := sleepContext(ctx, clock, delay); ctxErr != nil {
+			res.ctxErr = ctxErr
+			return giveUp(ctxErr)
+		}
+	}
+}
+
+// nextDelay returns the delay after the specified failed attempt (1-based);
+// previous is the delay returned for the previous attempt.
+func (policy RetryPolicy) nextDelay(attempt int, previous time.Duration) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	maxDelay := time.Duration(math.MaxInt64)
+	if policy.MaxDelay > 0 {
+		maxDelay = policy.MaxDelay
+	}
+
+	if policy.Jitter == JitterDecorrelated {
+		if previous <= 0 {
+			previous = policy.InitialDelay
+		}
+		return randomDurationBetween(policy.InitialDelay, clampDelay(float64(previous)*3, maxDelay))
+	}
+
+	// float64 math avoids overflowing for large numbers of attempts:
+	computed := float64(policy.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	delay := clampDelay(computed, maxDelay)
+
+	switch policy.Jitter {
+	case JitterFull:
+		return randomDurationBetween(0, delay)
+	case JitterEqual:
+		return delay/2 + randomDurationBetween(0, delay-delay/2)
+	default:
+		return delay
+	}
+}
+
+// clampDelay converts a delay computed with float64 math to a duration,
+// capped at maxDelay; float64(math.MaxInt64) rounds up to 2^63, so
+// converting it directly would overflow to a negative duration.
+func clampDelay(computed float64, maxDelay time.Duration) time.Duration {
+	if computed >= float64(maxDelay) {
+		return maxDelay
+	}
+	return time.Duration(computed)
+}
+
+// randomDurationBetween returns a random duration in [min, max].
+func randomDurationBetween(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	if max-min == math.MaxInt64 {
+		// the span+1 would overflow.
+		return min + time.Duration(rand.Int63())
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
+
+// sleepContext sleeps on the clock for the duration d, or until ctx is done;
+// in the latter case, the error of the context is returned.
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
@@ -0,0 +1,266 @@
+package utilz
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownHookTimeout is the timeout of the shutdown hooks
+// registered with a zero timeout.
+var DefaultShutdownHookTimeout = 30 * time.Second
+
+// ShutdownManager coordinates the graceful shutdown of a program:
+// it owns a root context that is canceled when the shutdown starts,
+// and runs the registered shutdown hooks.
+//
+// The hooks run in order of priority (lower first); hooks with the same
+// priority run concurrently. A hook that does not return within its
+// timeout is reported as hung, and the shutdown moves on.
+type ShutdownManager struct {
+	ctx    context.Context
+	cancel func()
+	light  *ExitLight
+
+	mu    *sync.Mutex
+	hooks []*shutdownHook
+
+	once   sync.Once
+	done   chan struct{}
+	report *ShutdownReport
+}
+
+type shutdownHook struct {
+	name     string
+	priority int
+	timeout  time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// NewShutdownManager returns a new ShutdownManager whose root context
+// is derived from parent.
+func NewShutdownManager(parent context.Context) *ShutdownManager {
+	ctx, cancel := context.WithCancel(parent)
+	return &ShutdownManager{
+		ctx:    ctx,
+		cancel: cancel,
+		light:  NewExitLight(),
+		mu:     &sync.Mutex{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Context returns the root context, which is canceled when the shutdown starts.
+func (m *ShutdownManager) Context() context.Context {
+	return m.ctx
+}
+
+// ExitLight returns the ExitLight that is set when the shutdown starts.
+func (m *ShutdownManager) ExitLight() *ExitLight {
+	return m.light
+}
+
+// IsExiting returns true if the shutdown has started.
+func (m *ShutdownManager) IsExiting() bool {
+	return m.light.IsExiting()
+}
+
+// Register registers a named shutdown hook; the hook's context is
+// canceled after the timeout (DefaultShutdownHookTimeout if zero).
+func (m *ShutdownManager) Register(name string, priority int, timeout time.Duration, fn func(ctx context.Context) error) {
+	if timeout <= 0 {
+		timeout = DefaultShutdownHookTimeout
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, &shutdownHook{
+		name:     name,
+		priority: priority,
+		timeout:  timeout,
+		fn:       fn,
+	})
+}
+
+// Shutdown starts the shutdown (if not already started), and waits
+// for it to complete; it is safe to call it multiple times.
+func (m *ShutdownManager) Shutdown() *ShutdownReport {
+	m.once.Do(func() {
+		go m.run()
+	})
+	return m.Wait()
+}
+
+// Done returns a channel that is closed when the shutdown has completed.
+func (m *ShutdownManager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Wait blocks until the shutdown has completed, and returns its report.
+func (m *ShutdownManager) Wait() *ShutdownReport {
+	<-m.done
+	return m.report
+}
+
+func (m *ShutdownManager) run() {
+	defer close(m.done)
+	m.light.SetAsExiting()
+	m.cancel()
+
+	m.mu.Lock()
+	hooks := append([]*shutdownHook(nil), m.hooks...)
+	m.mu.Unlock()
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].priority < hooks[j].priority
+	})
+
+	report := &ShutdownReport{
+		Results: make([]ShutdownHookResult, len(hooks)),
+	}
+	startedAt := time.Now()
+	for start := 0; start < len(hooks); {
+		// run all the hooks with the same priority concurrently:
+		end := start
+		for end < len(hooks) && hooks[end].priority == hooks[start].priority {
+			end++
+		}
+		wg := new(sync.WaitGroup)
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				report.Results[i] = runShutdownHook(hooks[i])
+			}(i)
+		}
+		wg.Wait()
+		start = end
+	}
+	report.Duration = time.Since(startedAt)
+	m.report = report
+}
+
+func runShutdownHook(hook *shutdownHook) ShutdownHookResult {
+	ctx, cancel := context.WithTimeout(context.Background(), hook.timeout)
+	defer cancel()
+
+	result := ShutdownHookResult{
+		Name:     hook.name,
+		Priority: hook.priority,
+	}
+	startedAt := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- callRecover(func() error {
+			return hook.fn(ctx)
+		})
+	}()
+	select {
+	case err := <-errCh:
+		result.Err = err
+	case <-ctx.Done():
+		// the hook did not return in time; it is left running.
+		result.Hung = true
+		result.Err = fmt.Errorf("shutdown hook %q did not return within %s", hook.name, hook.timeout)
+	}
+	result.Duration = time.Since(startedAt)
+	return result
+}
+
+// HandleSignals starts the shutdown at the first SIGINT or SIGTERM (or the
+// provided signals), and forces an exit (os.Exit(1)) at the second one,
+// as HardWaitSystemSignal does. The returned function stops the handling.
+func (m *ShutdownManager) HandleSignals(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = DefaultSignals
+	}
+	pipe := make(chan os.Signal, 2)
+	signal.Notify(pipe, signals...)
+
+	quit := make(chan struct{})
+	go func() {
+		signalNum := 0
+		for {
+			select {
+			case <-quit:
+				return
+			case sig := <-pipe:
+				signalNum++
+				if signalNum == 1 {
+					fmt.Fprintf(os.Stderr, "Received %v; shutting down\n", sig)
+					m.once.Do(func() {
+						go m.run()
+					})
+				} else {
+					Ln("Forcing exit")
+					os.Exit(1)
+				}
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() {
+			signal.Stop(pipe)
+			close(quit)
+		})
+	}
+}
+
+// ShutdownReport describes the outcome of a shutdown.
+type ShutdownReport struct {
+	Results  []ShutdownHookResult
+	Duration time.Duration
+}
+
+// ShutdownHookResult describes the outcome of a shutdown hook.
+type ShutdownHookResult struct {
+	Name     string
+	Priority int
+	Duration time.Duration
+	Err      error
+	// Hung is true if the hook did not return within its timeout.
+	Hung bool
+}
+
+// Hung returns the names of the hooks that did not return within their timeout.
+func (r *ShutdownReport) Hung() []string {
+	var names []string
+	for _, res := range r.Results {
+		if res.Hung {
+			names = append(names, res.Name)
+		}
+	}
+	return names
+}
+
+// Err returns the combined errors of the hooks, or nil.
+func (r *ShutdownReport) Err() error {
+	var errs []error
+	for _, res := range r.Results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Name, res.Err))
+		}
+	}
+	return CombineErrors(errs...)
+}
+
+func (r *ShutdownReport) String() string {
+	buf := new(strings.Builder)
+	buf.WriteString(Sf("shutdown completed in %s", formatDuration(r.Duration)))
+	for _, res := range r.Results {
+		status := Lime(Checkmark)
+		switch {
+		case res.Hung:
+			status = RedBG("HUNG")
+		case res.Err != nil:
+			status = Red(XMark) + " " + res.Err.Error()
+		}
+		buf.WriteString(Sf("\n - [%v] %s (%s): %s", res.Priority, res.Name, formatDuration(res.Duration), status))
+	}
+	return buf.String()
+}
@@ -2,7 +2,10 @@ package utilz
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/sync/semaphore"
 )
@@ -13,7 +16,11 @@ func NewSizedGroup(cNum int64) *SizedGroup {
 	return gr
 }
 
+// SizedGroup runs functions in goroutines, with at most a fixed number
+// of them running at the same time. Panics inside the functions are
+// recovered and returned as *PanicError errors.
 type SizedGroup struct {
+	ctx    context.Context
 	cancel func()
 
 	wg  sync.WaitGroup
@@ -21,44 +28,163 @@ type SizedGroup struct {
 
 	errOnce sync.Once
 	err     error
+
+	// collector, if not nil, collects all the errors.
+	collector *ErrorCollector
+
+	inFlight  int64
+	completed int64
 }
 
+// SizedGroupWithContext returns a new group with no limit on the number
+// of concurrent functions, and a derived context that is canceled the first
+// time a function returns a non-nil error or the first time Wait returns,
+// whichever occurs first.
 func SizedGroupWithContext(ctx context.Context) (*SizedGroup, context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
-	return &SizedGroup{cancel: cancel}, ctx
+	return &SizedGroup{ctx: ctx, cancel: cancel}, ctx
+}
+
+// NewSizedGroupWithContext is like SizedGroupWithContext, but runs at most
+// cNum functions at the same time.
+func NewSizedGroupWithContext(ctx context.Context, cNum int64) (*SizedGroup, context.Context) {
+	gr, ctx := SizedGroupWithContext(ctx)
+	gr.sem = semaphore.NewWeighted(cNum)
+	return gr, ctx
+}
+
+// CollectAllErrors makes Wait return all the errors (see ErrorCollector)
+// instead of only the first one; in this mode, errors don't cancel the group's
+// context. Must be called before Go.
+func (g *SizedGroup) CollectAllErrors() *SizedGroup {
+	g.collector = NewErrorCollector(0)
+	return g
 }
 
 // Wait blocks until all function calls from the Go method have returned, then
-// returns the first non-nil error (if any) from them.
+// returns the first non-nil error (if any) from them, or all of them if
+// CollectAllErrors was called.
 func (g *SizedGroup) Wait() error {
 	g.wg.Wait()
 	if g.cancel != nil {
 		g.cancel()
 	}
+	if g.collector != nil {
+		return g.collector.Err()
+	}
 	return g.err
 }
 
-// Go calls the given function in a new goroutine.
+// Go calls the given function in a new goroutine, waiting for a free slot
+// if the maximum number of functions are already running.
+//
+// If the group's context is done before the function is started, the function
+// is not called, and the context's error is recorded as the function's error.
 //
 // The first call to return a non-nil error cancels the group; its error will be
 // returned by Wait.
 func (g *SizedGroup) Go(f func() error) {
 	g.wg.Add(1)
 
-	if err := g.sem.Acquire(context.Background(), 1); err != nil {
-		panic(err)
+	if err := g.acquire(); err != nil {
+		g.setError(err)
+		g.wg.Done()
+		return
 	}
+	atomic.AddInt64(&g.inFlight, 1)
 	go func() {
 		defer g.wg.Done()
-		defer g.sem.Release(1)
-
-		if err := f(); err != nil {
-			g.errOnce.Do(func() {
-				g.err = err
-				if g.cancel != nil {
-					g.cancel()
-				}
-			})
+		defer func() {
+			atomic.AddInt64(&g.inFlight, -1)
+			atomic.AddInt64(&g.completed, 1)
+		}()
+		if g.sem != nil {
+			defer g.sem.Release(1)
+		}
+
+		if err := callRecover(f); err != nil {
+			g.setError(err)
+		}
+	}()
+}
+
+// acquire waits for a free slot (if the group is bounded); it fails
+// if the group's context is done, even if a slot is free.
+func (g *SizedGroup) acquire() error {
+	if g.ctx == nil {
+		if g.sem != nil {
+			return g.sem.Acquire(context.Background(), 1)
+		}
+		return nil
+	}
+	if err := g.ctx.Err(); err != nil {
+		return err
+	}
+	if g.sem == nil {
+		return nil
+	}
+	if err := g.sem.Acquire(g.ctx, 1); err != nil {
+		return err
+	}
+	// Acquire succeeds right away when a slot is free,
+	// even if the context was canceled while waiting.
+	if err := g.ctx.Err(); err != nil {
+		g.sem.Release(1)
+		return err
+	}
+	return nil
+}
+
+func (g *SizedGroup) setError(err error) {
+	if g.collector != nil {
+		g.collector.Add(err)
+		return
+	}
+	g.errOnce.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel()
+		}
+	})
+}
+
+// InFlight returns the number of functions that are currently running.
+func (g *SizedGroup) InFlight() int64 {
+	return atomic.LoadInt64(&g.inFlight)
+}
+
+// Completed returns the number of functions that have returned.
+func (g *SizedGroup) Completed() int64 {
+	return atomic.LoadInt64(&g.completed)
+}
+
+// PanicError is the error of a function that panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// callRecover calls f, and converts a panic into a *PanicError.
+func callRecover(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Value: r,
+				Stack: debug.Stack(),
+			}
 		}
 	}()
+	return f()
 }
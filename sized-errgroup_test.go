@@ -0,0 +1,152 @@
+package utilz
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSizedGroupGoAfterCancel(t *testing.T) {
+	for _, bounded := range []bool{true, false} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var g *SizedGroup
+		if bounded {
+			g, _ = NewSizedGroupWithContext(ctx, 2)
+		} else {
+			g, _ = SizedGroupWithContext(ctx)
+		}
+		cancel()
+
+		var called int32
+		g.Go(func() error {
+			atomic.StoreInt32(&called, 1)
+			return nil
+		})
+		if err := g.Wait(); !errors.Is(err, context.Canceled) {
+			t.Errorf("bounded=%v: got %v, expected context.Canceled", bounded, err)
+		}
+		if atomic.LoadInt32(&called) != 0 {
+			t.Errorf("bounded=%v: the function was called after cancel", bounded)
+		}
+	}
+}
+
+func TestSizedGroupFirstErrorCancels(t *testing.T) {
+	g, ctx := NewSizedGroupWithContext(context.Background(), 1)
+	failing := errors.New("failing")
+	g.Go(func() error {
+		return failing
+	})
+	<-ctx.Done()
+	// the group is canceled: the next functions are not called.
+	g.Go(func() error {
+		t.Error("called after the group was canceled")
+		return nil
+	})
+	if err := g.Wait(); err != failing {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestSizedGroupPanic(t *testing.T) {
+	g := NewSizedGroup(2)
+	cause := errors.New("cause")
+	g.Go(func() error {
+		panic(cause)
+	})
+	err := g.Wait()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("got %v, expected a *PanicError", err)
+	}
+	if panicErr.Value != cause || len(panicErr.Stack) == 0 {
+		t.Fatalf("got %+v", panicErr)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatal("the panic value is not unwrapped")
+	}
+}
+
+func TestSizedGroupCollectAllErrors(t *testing.T) {
+	g, ctx := NewSizedGroupWithContext(context.Background(), 3)
+	g.CollectAllErrors()
+	first, second := errors.New("first"), errors.New("second")
+	for i := 0; i < 10; i++ {
+		err := first
+		if i%2 == 1 {
+			err = second
+		}
+		g.Go(func() error {
+			return err
+		})
+	}
+	err := g.Wait()
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("got %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("the context is not canceled after Wait")
+	}
+	if n := g.Completed(); n != 10 {
+		t.Fatalf("got %v completed, expected 10 (errors must not cancel the group)", n)
+	}
+}
+
+func TestSizedGroupCounters(t *testing.T) {
+	const limit = 3
+	g := NewSizedGroup(limit)
+	release := make(chan struct{})
+	started := &sync.WaitGroup{}
+	started.Add(limit)
+	for i := 0; i < limit; i++ {
+		g.Go(func() error {
+			started.Done()
+			<-release
+			return nil
+		})
+	}
+	started.Wait()
+	if n := g.InFlight(); n != limit {
+		t.Fatalf("got %v in flight, expected %v", n, limit)
+	}
+	if n := g.Completed(); n != 0 {
+		t.Fatalf("got %v completed, expected 0", n)
+	}
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := g.InFlight(); n != 0 {
+		t.Fatalf("got %v in flight after Wait", n)
+	}
+	if n := g.Completed(); n != limit {
+		t.Fatalf("got %v completed, expected %v", n, limit)
+	}
+}
+
+func TestSizedGroupLimit(t *testing.T) {
+	const limit = 2
+	g := NewSizedGroup(limit)
+	var running, maxRunning int64
+	mu := &sync.Mutex{}
+	for i := 0; i < 20; i++ {
+		g.Go(func() error {
+			n := atomic.AddInt64(&running, 1)
+			mu.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			mu.Unlock()
+			atomic.AddInt64(&running, -1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if maxRunning > limit {
+		t.Fatalf("got %v concurrent functions, expected at most %v", maxRunning, limit)
+	}
+}
@@ -0,0 +1,139 @@
+package utilz
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandlerOptions are the options of a SlogHandler.
+type SlogHandlerOptions struct {
+	// Level is the minimum level of the records that are handled;
+	// if nil, the level of the Logger is used.
+	Level slog.Leveler
+}
+
+// SlogHandler is a slog.Handler that writes records with a Logger,
+// i.e. with the utilz level prefixes and the `prefix[a|b|c] message [k=v]` format.
+type SlogHandler struct {
+	logger *Logger
+	level  slog.Leveler
+
+	// groups is the prefix (e.g. "a.b.") of the keys of the record attributes.
+	groups string
+	// attrs are the key/value pairs added with WithAttrs.
+	attrs []interface{}
+}
+
+var _ slog.Handler = &SlogHandler{}
+
+// NewSlogHandler returns a slog.Handler that writes to the provided logger;
+// if logger is nil, the default logger is used.
+func NewSlogHandler(logger *Logger, opts *SlogHandlerOptions) *SlogHandler {
+	if logger == nil {
+		logger = defaultLogger
+	}
+	if opts == nil {
+		opts = &SlogHandlerOptions{}
+	}
+	return &SlogHandler{
+		logger: logger,
+		level:  opts.Level,
+	}
+}
+
+// NewSlogLogger returns a *slog.Logger that writes to the provided logger.
+func NewSlogLogger(logger *Logger, opts *SlogHandlerOptions) *slog.Logger {
+	return slog.New(NewSlogHandler(logger, opts))
+}
+
+// SlogLevelToLogLevel converts a slog.Level to the LogLevel that
+// has the same prefix.
+func SlogLevelToLogLevel(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return LogLevelDebug
+	case level < slog.LevelWarn:
+		return LogLevelInfo
+	case level < slog.LevelError:
+		return LogLevelWarn
+	default:
+		return LogLevelError
+	}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.level != nil {
+		return level >= h.level.Level() && h.logger.Enabled(SlogLevelToLogLevel(level))
+	}
+	return h.logger.Enabled(SlogLevelToLogLevel(level))
+}
+
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	keyvals := make([]interface{}, 0, len(h.attrs)+r.NumAttrs()*2)
+	keyvals = append(keyvals, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		keyvals = appendSlogAttr(keyvals, h.groups, a)
+		return true
+	})
+
+	level := SlogLevelToLogLevel(r.Level)
+	msg := r.Message
+	if level == LogLevelError {
+		msg = RedBG(msg)
+	}
+	h.logger.write(
+		level,
+		newParamsWithLogLevel(level),
+		msg,
+		keyvals,
+		r.PC,
+		r.Time,
+	)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = make([]interface{}, 0, len(h.attrs)+len(attrs)*2)
+	clone.attrs = append(clone.attrs, h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = appendSlogAttr(clone.attrs, h.groups, a)
+	}
+	return &clone
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = h.groups + name + "."
+	return &clone
+}
+
+// appendSlogAttr appends the attribute to keyvals as key/value pairs,
+// flattening groups into dotted keys.
+func appendSlogAttr(keyvals []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return keyvals
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		groupAttrs := a.Value.Group()
+		if len(groupAttrs) == 0 {
+			return keyvals
+		}
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range groupAttrs {
+			keyvals = appendSlogAttr(keyvals, groupPrefix, ga)
+		}
+		return keyvals
+	}
+	return append(keyvals, prefix+a.Key, a.Value.Any())
+}
@@ -0,0 +1,82 @@
+package utilz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+// parseJSONLogLines parses the JSON log messages in buf, expanding the
+// dotted keys written for the slog groups into nested objects.
+func parseJSONLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var res []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var flat map[string]interface{}
+		if err := json.Unmarshal(line, &flat); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		m := make(map[string]interface{})
+		for k, v := range flat {
+			parts := strings.Split(k, ".")
+			group := m
+			for _, part := range parts[:len(parts)-1] {
+				sub, ok := group[part].(map[string]interface{})
+				if !ok {
+					sub = make(map[string]interface{})
+					group[part] = sub
+				}
+				group = sub
+			}
+			group[parts[len(parts)-1]] = v
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestSlogHandlerConformance(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(buf)
+	l.SetEncoding(LogEncodingJSON)
+	err := slogtest.TestHandler(NewSlogHandler(l, nil), func() []map[string]interface{} {
+		return parseJSONLogLines(t, buf)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSlogHandlerRecordTimeAndCaller(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(buf)
+	l.SetParameters(LogParamTimestamp, LogParamCallStack)
+	h := NewSlogHandler(l, nil)
+
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	at := time.Date(2024, 1, 1, 13, 14, 15, 0, time.Local)
+	if err := h.Handle(context.Background(), slog.NewRecord(at, slog.LevelInfo, "hello", pcs[0])); err != nil {
+		t.Fatal(err)
+	}
+	expected := Sf("[13:14:15|slog_test.go:%v]", frame.Line)
+	if got := StripANSI(buf.String()); !strings.Contains(got, expected) {
+		t.Fatalf("got %q, expected the header %q", got, expected)
+	}
+
+	// a zero record time is omitted:
+	buf.Reset()
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if got := StripANSI(buf.String()); !strings.Contains(got, "[] hello") {
+		t.Fatalf("got %q, expected an empty header", got)
+	}
+}
@@ -0,0 +1,246 @@
+package utilz
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Stopwatch measures the elapsed time of a job, and of its named laps;
+// the time spent stopped is not counted. It is safe for concurrent use.
+type Stopwatch struct {
+	mu    *sync.Mutex
+	clock Clock
+
+	running bool
+	// resumedAt is when the stopwatch was last started or resumed.
+	resumedAt time.Time
+	// elapsed is the time counted before resumedAt.
+	elapsed time.Duration
+	// lapStart is the elapsed time at which the current lap started.
+	lapStart time.Duration
+	laps     []Lap
+}
+
+// Lap is a lap recorded by a Stopwatch.
+type Lap struct {
+	Name string `json:"name"`
+	// Duration is the time from the end of the previous lap.
+	Duration time.Duration `json:"duration_ns"`
+	// Split is the elapsed time of the stopwatch at the end of the lap.
+	Split time.Duration `json:"split_ns"`
+}
+
+// LapStats are the statistics of the laps with the same name.
+type LapStats struct {
+	Name  string        `json:"name"`
+	Count int           `json:"count"`
+	Total time.Duration `json:"total_ns"`
+	Min   time.Duration `json:"min_ns"`
+	Max   time.Duration `json:"max_ns"`
+	Avg   time.Duration `json:"avg_ns"`
+}
+
+// StopwatchReport is a snapshot of a Stopwatch.
+type StopwatchReport struct {
+	Running bool          `json:"running"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+	Laps    []Lap         `json:"laps"`
+	Stats   []LapStats    `json:"stats"`
+}
+
+// NewStopwatch returns a new stopped Stopwatch.
+func NewStopwatch() *Stopwatch {
+	return NewStopwatchWithClock(nil)
+}
+
+// NewStopwatchWithClock is like NewStopwatch, but uses the provided clock.
+func NewStopwatchWithClock(clock Clock) *Stopwatch {
+	return &Stopwatch{
+		mu:    &sync.Mutex{},
+		clock: orRealClock(clock),
+	}
+}
+
+// StartStopwatch returns a new running Stopwatch.
+func StartStopwatch() *Stopwatch {
+	return NewStopwatch().Start()
+}
+
+// Start resets the stopwatch (discarding all the laps), and starts it.
+func (sw *Stopwatch) Start() *Stopwatch {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.elapsed = 0
+	sw.lapStart = 0
+	sw.laps = nil
+	sw.running = true
+	sw.resumedAt = sw.clock.Now()
+	return sw
+}
+
+// Stop stops the stopwatch, and returns the elapsed time;
+// it does nothing if already stopped.
+func (sw *Stopwatch) Stop() time.Duration {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.running {
+		sw.elapsed = sw.elapsedLocked()
+		sw.running = false
+	}
+	return sw.elapsed
+}
+
+// Resume restarts a stopped stopwatch without resetting it;
+// it does nothing if already running.
+func (sw *Stopwatch) Resume() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.running {
+		return
+	}
+	sw.running = true
+	sw.resumedAt = sw.clock.Now()
+}
+
+// Running returns true if the stopwatch is running.
+func (sw *Stopwatch) Running() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.running
+}
+
+// Elapsed returns the total time counted by the stopwatch.
+func (sw *Stopwatch) Elapsed() time.Duration {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.elapsedLocked()
+}
+
+// elapsedLocked must be called with sw.mu held.
+func (sw *Stopwatch) elapsedLocked() time.Duration {
+	if !sw.running {
+		return sw.elapsed
+	}
+	return sw.elapsed + sw.clock.Now().Sub(sw.resumedAt)
+}
+
+// Lap ends the current lap with the provided name (and starts a new one).
+func (sw *Stopwatch) Lap(name string) Lap {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	split := sw.elapsedLocked()
+	lap := Lap{
+		Name:     name,
+		Duration: split - sw.lapStart,
+		Split:    split,
+	}
+	sw.lapStart = split
+	sw.laps = append(sw.laps, lap)
+	return lap
+}
+
+// Time records the execution of fn as a lap; the time passed since
+// the end of the previous lap is not counted.
+func (sw *Stopwatch) Time(name string, fn func()) Lap {
+	sw.mu.Lock()
+	sw.lapStart = sw.elapsedLocked()
+	sw.mu.Unlock()
+	fn()
+	return sw.Lap(name)
+}
+
+// Laps returns the recorded laps.
+func (sw *Stopwatch) Laps() []Lap {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return append([]Lap(nil), sw.laps...)
+}
+
+// LapStats returns the statistics of the laps grouped by name,
+// in order of first appearance.
+func (sw *Stopwatch) LapStats() []LapStats {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return computeLapStats(sw.laps)
+}
+
+func computeLapStats(laps []Lap) []LapStats {
+	var stats []LapStats
+	indexes := make(map[string]int)
+	for _, lap := range laps {
+		i, ok := indexes[lap.Name]
+		if !ok {
+			i = len(stats)
+			indexes[lap.Name] = i
+			stats = append(stats, LapStats{
+				Name: lap.Name,
+				Min:  lap.Duration,
+				Max:  lap.Duration,
+			})
+		}
+		st := &stats[i]
+		st.Count++
+		st.Total += lap.Duration
+		if lap.Duration < st.Min {
+			st.Min = lap.Duration
+		}
+		if lap.Duration > st.Max {
+			st.Max = lap.Duration
+		}
+	}
+	for i := range stats {
+		stats[i].Avg = stats[i].Total / time.Duration(stats[i].Count)
+	}
+	return stats
+}
+
+// Report returns a snapshot of the stopwatch.
+func (sw *Stopwatch) Report() *StopwatchReport {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return &StopwatchReport{
+		Running: sw.running,
+		Elapsed: sw.elapsedLocked(),
+		Laps:    append([]Lap{}, sw.laps...),
+		Stats:   append([]LapStats{}, computeLapStats(sw.laps)...),
+	}
+}
+
+// MarshalJSON exports the report of the stopwatch as JSON
+// (the durations are in nanoseconds).
+func (sw *Stopwatch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sw.Report())
+}
+
+// Summary returns a table with the statistics of the laps, and the total elapsed time.
+func (sw *Stopwatch) Summary() string {
+	return sw.Report().String()
+}
+
+func (r *StopwatchReport) String() string {
+	table := NewTable("lap", "count", "total", "min", "max", "avg", "%")
+	for col := 1; col < 7; col++ {
+		table.SetAlign(col, AlignRight)
+	}
+	for _, st := range r.Stats {
+		percent := 0.0
+		if r.Elapsed > 0 {
+			percent = float64(st.Total) / float64(r.Elapsed) * 100
+		}
+		table.AddRow(
+			st.Name,
+			st.Count,
+			formatDuration(st.Total),
+			formatDuration(st.Min),
+			formatDuration(st.Max),
+			formatDuration(st.Avg),
+			Sf("%.1f", percent),
+		)
+	}
+	status := "stopped"
+	if r.Running {
+		status = "running"
+	}
+	return table.String() + Sf("elapsed: %s (%s)", formatDuration(r.Elapsed), status)
+}
@@ -0,0 +1,259 @@
+package utilz
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ansiEscapeRegex matches the CSI (e.g. colors) and OSC (e.g. hyperlinks)
+// ANSI escape sequences.
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// StripANSI removes the ANSI escape sequences (e.g. colors) from s.
+func StripANSI(s string) string {
+	if !strings.ContainsRune(s, '\x1b') {
+		return s
+	}
+	return ansiEscapeRegex.ReplaceAllString(s, "")
+}
+
+// VisibleWidth returns the number of terminal cells that s occupies
+// when printed; ANSI escape sequences have zero width, and
+// East-Asian wide characters (and most emoji) have a width of 2.
+func VisibleWidth(s string) int {
+	s = StripANSI(s)
+	width := 0
+	for _, r := range s {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// RuneWidth returns the number of terminal cells occupied by the rune.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		// control characters:
+		return 0
+	case r < 0x300:
+		// fast path for latin:
+		return 1
+	case r >= 0x1160 && r <= 0x11ff:
+		// Hangul Jamo medial vowels and final consonants:
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		// combining marks, zero-width joiners, variation selectors:
+		return 0
+	case isWideRune(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// wideRuneRanges are the (sorted, inclusive) ranges of the runes
+// that are East-Asian Wide or Fullwidth, including the emoji
+// that are rendered wide by default.
+var wideRuneRanges = [][2]rune{
+	{0x1100, 0x115f},
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x23f0, 0x23f0},
+	{0x23f3, 0x23f3},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267f, 0x267f},
+	{0x2693, 0x2693},
+	{0x26a1, 0x26a1},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x26ce, 0x26ce},
+	{0x26d4, 0x26d4},
+	{0x26ea, 0x26ea},
+	{0x26f2, 0x26f3},
+	{0x26f5, 0x26f5},
+	{0x26fa, 0x26fa},
+	{0x26fd, 0x26fd},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x274e, 0x274e},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27b0, 0x27b0},
+	{0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xa960, 0xa97f},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x16fe0, 0x16fe4},
+	{0x17000, 0x18aff},
+	{0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f251},
+	{0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335},
+	{0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393},
+	{0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3},
+	{0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440},
+	{0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d},
+	{0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567},
+	{0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f},
+	{0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc},
+	{0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7},
+	{0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc},
+	{0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff},
+	{0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
+
+func isWideRune(r rune) bool {
+	i := sort.Search(len(wideRuneRanges), func(i int) bool {
+		return wideRuneRanges[i][1] >= r
+	})
+	return i < len(wideRuneRanges) && wideRuneRanges[i][0] <= r
+}
+
+// Alignment is the horizontal alignment of a string inside a fixed width.
+type Alignment int
+
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCenter
+)
+
+// PadRight pads s with spaces on the right up to the visible width;
+// s is returned as-is if it is already wide enough.
+func PadRight(s string, width int) string {
+	return Pad(s, width, AlignLeft)
+}
+
+// PadLeft pads s with spaces on the left up to the visible width;
+// s is returned as-is if it is already wide enough.
+func PadLeft(s string, width int) string {
+	return Pad(s, width, AlignRight)
+}
+
+// PadCenter pads s with spaces on both sides up to the visible width;
+// s is returned as-is if it is already wide enough.
+func PadCenter(s string, width int) string {
+	return Pad(s, width, AlignCenter)
+}
+
+// Pad pads s with spaces up to the visible width, with the specified alignment.
+func Pad(s string, width int, align Alignment) string {
+	missing := width - VisibleWidth(s)
+	if missing <= 0 {
+		return s
+	}
+	switch align {
+	case AlignRight:
+		return ReturnNSpaces(missing) + s
+	case AlignCenter:
+		left := missing / 2
+		return ReturnNSpaces(left) + s + ReturnNSpaces(missing-left)
+	default:
+		return s + ReturnNSpaces(missing)
+	}
+}
+
+// Ellipsis is the suffix added to the truncated strings by TruncateWithEllipsis.
+const Ellipsis = "…"
+
+// Truncate cuts s so that its visible width is at most width;
+// ANSI escape sequences are preserved.
+func Truncate(s string, width int) string {
+	return TruncateWithTail(s, width, "")
+}
+
+// TruncateWithEllipsis is like Truncate, but terminates the
+// truncated strings with an ellipsis.
+func TruncateWithEllipsis(s string, width int) string {
+	return TruncateWithTail(s, width, Ellipsis)
+}
+
+// TruncateWithTail cuts s so that its visible width (including the tail)
+// is at most width, and appends the tail to it;
+// if s is not wider than width, it is returned as-is.
+// If s contains ANSI escape sequences, a reset sequence is added
+// before the tail so that colors don't bleed. A negative width is treated as 0.
+func TruncateWithTail(s string, width int, tail string) string {
+	if width < 0 {
+		width = 0
+	}
+	if VisibleWidth(s) <= width {
+		return s
+	}
+	tailWidth := VisibleWidth(tail)
+	if tailWidth > width {
+		tail = Truncate(tail, width)
+		tailWidth = VisibleWidth(tail)
+	}
+	maxWidth := width - tailWidth
+
+	var buf strings.Builder
+	escapes := ansiEscapeRegex.FindAllStringIndex(s, -1)
+	hasEscapes := len(escapes) > 0
+	currentWidth := 0
+	for i := 0; i < len(s); {
+		if len(escapes) > 0 && escapes[0][0] == i {
+			buf.WriteString(s[escapes[0][0]:escapes[0][1]])
+			i = escapes[0][1]
+			escapes = escapes[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := RuneWidth(r)
+		if currentWidth+rw > maxWidth {
+			break
+		}
+		currentWidth += rw
+		buf.WriteString(s[i : i+size])
+		i += size
+	}
+	if hasEscapes {
+		buf.WriteString("\x1b[0m")
+	}
+	buf.WriteString(tail)
+	return buf.String()
+}
@@ -0,0 +1,127 @@
+package utilz
+
+import (
+	"testing"
+)
+
+const (
+	testRed   = "\x1b[31m"
+	testReset = "\x1b[0m"
+)
+
+func TestVisibleWidth(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{testRed + "red" + testReset, 3},
+		{"\x1b[1;38;2;252;255;43mbold" + testReset, 4},
+		// OSC 8 hyperlink:
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", 4},
+		{"日本語", 6},
+		{"한국", 4},
+		{"ＡＢ", 4},
+		{"😀", 2},
+		{"a😀b", 4},
+		// combining acute accent:
+		{"e\u0301", 1},
+		// zero-width joiner:
+		{"a\u200db", 2},
+		{"tab\there", 7},
+		{testRed + "日本" + testReset, 4},
+	}
+	for _, c := range cases {
+		if got := VisibleWidth(c.s); got != c.expected {
+			t.Errorf("VisibleWidth(%q) = %v, expected %v", c.s, got, c.expected)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	colored := testRed + "ab" + testReset
+	cases := []struct {
+		s        string
+		width    int
+		align    Alignment
+		expected string
+	}{
+		{"ab", 5, AlignLeft, "ab   "},
+		{"ab", 5, AlignRight, "   ab"},
+		{"ab", 5, AlignCenter, " ab  "},
+		{"日本", 6, AlignLeft, "日本  "},
+		{"日本", 6, AlignRight, "  日本"},
+		{"😀", 4, AlignCenter, " 😀 "},
+		{colored, 4, AlignLeft, colored + "  "},
+		{colored, 4, AlignRight, "  " + colored},
+		// already wide enough:
+		{"abc", 2, AlignLeft, "abc"},
+		{"日本", 3, AlignRight, "日本"},
+		{"abc", 0, AlignCenter, "abc"},
+		{"abc", -1, AlignLeft, "abc"},
+	}
+	for _, c := range cases {
+		if got := Pad(c.s, c.width, c.align); got != c.expected {
+			t.Errorf("Pad(%q, %v, %v) = %q, expected %q", c.s, c.width, c.align, got, c.expected)
+		}
+	}
+	if got := PadRight("ab", 3); got != "ab " {
+		t.Errorf("PadRight = %q", got)
+	}
+	if got := PadLeft("ab", 3); got != " ab" {
+		t.Errorf("PadLeft = %q", got)
+	}
+	if got := PadCenter("ab", 4); got != " ab " {
+		t.Errorf("PadCenter = %q", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		s        string
+		width    int
+		tail     string
+		expected string
+	}{
+		{"hello", 10, "", "hello"},
+		{"hello", 5, "", "hello"},
+		{"hello", 3, "", "hel"},
+		{"hello", 0, "", ""},
+		// a wide rune that doesn't fit is dropped entirely:
+		{"日本語", 5, "", "日本"},
+		{"日本語", 6, "", "日本語"},
+		{"😀😀😀", 3, "", "😀"},
+		{"e\u0301e\u0301e\u0301", 2, "", "e\u0301e\u0301"},
+		// colors are preserved and reset before the tail:
+		{testRed + "hello" + testReset, 2, "", testRed + "he" + testReset},
+		{testRed + "hello" + testReset, 5, "", testRed + "hello" + testReset},
+		{testRed + "日本語" + testReset, 4, Ellipsis, testRed + "日" + testReset + Ellipsis},
+		{"hello", 4, Ellipsis, "hel" + Ellipsis},
+		{"hello", 1, Ellipsis, Ellipsis},
+		{"hello", 0, Ellipsis, ""},
+		{"😀😀😀", 5, Ellipsis, "😀😀" + Ellipsis},
+		// the tail is truncated too if it doesn't fit:
+		{"hello", 2, "...", ".."},
+		{"hello", 4, " [more]", " [mo"},
+		// a negative width is treated as 0:
+		{"hello", -1, "", ""},
+		{"hello", -5, Ellipsis, ""},
+		{"", -1, Ellipsis, ""},
+	}
+	for _, c := range cases {
+		got := TruncateWithTail(c.s, c.width, c.tail)
+		if got != c.expected {
+			t.Errorf("TruncateWithTail(%q, %v, %q) = %q, expected %q", c.s, c.width, c.tail, got, c.expected)
+		}
+		if w := VisibleWidth(got); w > c.width && w > 0 {
+			t.Errorf("%q is wider than %v", got, c.width)
+		}
+	}
+	if got := Truncate("hello", 2); got != "he" {
+		t.Errorf("Truncate = %q", got)
+	}
+	if got := TruncateWithEllipsis("hello", 2); got != "h"+Ellipsis {
+		t.Errorf("TruncateWithEllipsis = %q", got)
+	}
+}
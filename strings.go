@@ -162,20 +162,14 @@ func GetAddedRemoved(previous []string, next []string) (added []string, removed
 
 var ConstantPredeterminedLength = 60
 
+// CustomConstantLength pads s with spaces up to the visible width ln;
+// ANSI escape codes and wide characters are taken into account.
 func CustomConstantLength(ln int, s string) string {
-	if len(s) < ln {
-		s += ReturnNSpaces(ln - len(s))
-		return s
-	}
-	return s
+	return PadRight(s, ln)
 }
 
 func ConstantLength(s string) string {
-	if len(s) < ConstantPredeterminedLength {
-		s += ReturnNSpaces(ConstantPredeterminedLength - len(s))
-		return s
-	}
-	return s
+	return CustomConstantLength(ConstantPredeterminedLength, s)
 }
 func RepeatString(n int, char string) string {
 	var res string
@@ -481,3 +475,21 @@ func ToStringKeyVals(keyvals ...string) (keys []string, vals []string) {
 	}
 	return
 }
+
+// ToKeyVals is like ToStringKeyVals, but accepts values of any type;
+// keys and values are converted to strings with fmt.Sprint.
+func ToKeyVals(keyvals ...interface{}) (keys []string, vals []string) {
+	if len(keyvals) == 0 {
+		return
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		k := fmt.Sprint(keyvals[i])
+		var v = "MISSING"
+		if i+1 < len(keyvals) {
+			v = fmt.Sprint(keyvals[i+1])
+		}
+		keys = append(keys, k)
+		vals = append(vals, v)
+	}
+	return
+}
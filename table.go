@@ -0,0 +1,358 @@
+package utilz
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TableStyle defines the characters used to draw the borders of a table.
+type TableStyle struct {
+	// Borders enables the outer borders and the line under the headers.
+	Borders bool
+
+	Horizontal string
+	Vertical   string
+
+	TopLeft     string
+	TopMid      string
+	TopRight    string
+	MidLeft     string
+	MidMid      string
+	MidRight    string
+	BottomLeft  string
+	BottomMid   string
+	BottomRight string
+
+	// ColumnSeparator is used between the columns when Borders is false.
+	ColumnSeparator string
+}
+
+var (
+	// TableStyleBox draws the borders with box-drawing characters.
+	TableStyleBox = TableStyle{
+		Borders:     true,
+		Horizontal:  "─",
+		Vertical:    "│",
+		TopLeft:     "┌",
+		TopMid:      "┬",
+		TopRight:    "┐",
+		MidLeft:     "├",
+		MidMid:      "┼",
+		MidRight:    "┤",
+		BottomLeft:  "└",
+		BottomMid:   "┴",
+		BottomRight: "┘",
+	}
+	// TableStyleASCII draws the borders with ASCII characters.
+	TableStyleASCII = TableStyle{
+		Borders:     true,
+		Horizontal:  "-",
+		Vertical:    "|",
+		TopLeft:     "+",
+		TopMid:      "+",
+		TopRight:    "+",
+		MidLeft:     "+",
+		MidMid:      "+",
+		MidRight:    "+",
+		BottomLeft:  "+",
+		BottomMid:   "+",
+		BottomRight: "+",
+	}
+	// TableStyleNone draws no borders; the columns are separated by spaces.
+	TableStyleNone = TableStyle{
+		ColumnSeparator: "  ",
+	}
+)
+
+// TableColumn is the configuration of a table column.
+type TableColumn struct {
+	Align Alignment
+	// MaxWidth is the maximum visible width of the cells of the column;
+	// longer cells are truncated with an ellipsis. Zero means no limit.
+	MaxWidth int
+	// Colorizer, if not nil, is applied to each cell of the column before
+	// truncation, e.g. Colorize or LimeBG; it is not applied to the header.
+	Colorizer func(string) string
+}
+
+// Table renders aligned rows of text; the widths of the columns
+// are computed on the visible width of the cells, so colored
+// and wide characters are aligned correctly.
+type Table struct {
+	headers  []string
+	rows     [][]string
+	columns  []TableColumn
+	style    TableStyle
+	maxWidth int
+
+	cellColorizer func(row, col int, cell string) string
+}
+
+// NewTable returns a new table with the provided headers, and the box style.
+func NewTable(headers ...string) *Table {
+	return &Table{
+		headers: headers,
+		columns: make([]TableColumn, len(headers)),
+		style:   TableStyleBox,
+	}
+}
+
+// AddRow adds a row; the cells are converted to strings with fmt.Sprint.
+func (t *Table) AddRow(cells ...interface{}) *Table {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = fmt.Sprint(cell)
+	}
+	t.rows = append(t.rows, row)
+	return t
+}
+
+// SetStyle sets the style of the borders.
+func (t *Table) SetStyle(style TableStyle) *Table {
+	t.style = style
+	return t
+}
+
+// SetColumn sets the configuration of the column at index col.
+func (t *Table) SetColumn(col int, column TableColumn) *Table {
+	t.ensureColumns(col + 1)
+	t.columns[col] = column
+	return t
+}
+
+// SetAlign sets the alignment of the column at index col.
+func (t *Table) SetAlign(col int, align Alignment) *Table {
+	t.ensureColumns(col + 1)
+	t.columns[col].Align = align
+	return t
+}
+
+// SetColorizer sets the colorizer of the column at index col.
+func (t *Table) SetColorizer(col int, colorizer func(string) string) *Table {
+	t.ensureColumns(col + 1)
+	t.columns[col].Colorizer = colorizer
+	return t
+}
+
+// SetCellColorizer sets a function that colors each cell;
+// it is applied after the column colorizer. The row index does not
+// include the header row.
+func (t *Table) SetCellColorizer(fn func(row, col int, cell string) string) *Table {
+	t.cellColorizer = fn
+	return t
+}
+
+// SetMaxWidth sets the maximum visible width of the whole rendered table;
+// the widest columns are shrunk until the table fits. Zero means no limit.
+func (t *Table) SetMaxWidth(width int) *Table {
+	t.maxWidth = width
+	return t
+}
+
+func (t *Table) ensureColumns(n int) {
+	for len(t.columns) < n {
+		t.columns = append(t.columns, TableColumn{})
+	}
+}
+
+func (t *Table) numColumns() int {
+	n := len(t.headers)
+	if len(t.columns) > n {
+		n = len(t.columns)
+	}
+	for _, row := range t.rows {
+		if len(row) > n {
+			n = len(row)
+		}
+	}
+	return n
+}
+
+func getCell(row []string, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return ""
+}
+
+// columnWidths computes the visible width of each column.
+func (t *Table) columnWidths(numCols int) []int {
+	widths := make([]int, numCols)
+	for col := range widths {
+		widths[col] = VisibleWidth(getCell(t.headers, col))
+		for _, row := range t.rows {
+			if w := VisibleWidth(getCell(row, col)); w > widths[col] {
+				widths[col] = w
+			}
+		}
+		if max := t.columns[col].MaxWidth; max > 0 && widths[col] > max {
+			widths[col] = max
+		}
+	}
+
+	if t.maxWidth > 0 {
+		// shrink the widest column, one cell at a time, until the table fits:
+		for t.totalWidth(widths) > t.maxWidth {
+			widest := 0
+			for col := range widths {
+				if widths[col] > widths[widest] {
+					widest = col
+				}
+			}
+			if widths[widest] <= 1 {
+				break
+			}
+			widths[widest]--
+		}
+	}
+	return widths
+}
+
+// totalWidth returns the visible width of a rendered line.
+func (t *Table) totalWidth(widths []int) int {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	if t.style.Borders {
+		// "│ " + cells separated by " │ " + " │"
+		return total + 3*len(widths) + 1
+	}
+	if len(widths) > 0 {
+		total += VisibleWidth(t.style.ColumnSeparator) * (len(widths) - 1)
+	}
+	return total
+}
+
+// Render writes the table to w.
+func (t *Table) Render(w io.Writer) error {
+	_, err := io.WriteString(w, t.String())
+	return err
+}
+
+// String returns the rendered table.
+func (t *Table) String() string {
+	numCols := t.numColumns()
+	t.ensureColumns(numCols)
+	widths := t.columnWidths(numCols)
+
+	buf := new(strings.Builder)
+	if t.style.Borders {
+		buf.WriteString(t.borderLine(widths, t.style.TopLeft, t.style.TopMid, t.style.TopRight))
+	}
+	if len(t.headers) > 0 {
+		cells := make([]string, numCols)
+		for col := range cells {
+			cell := TruncateWithEllipsis(getCell(t.headers, col), widths[col])
+			cells[col] = Pad(Bold(cell), widths[col], t.columns[col].Align)
+		}
+		buf.WriteString(t.line(cells))
+		if t.style.Borders {
+			buf.WriteString(t.borderLine(widths, t.style.MidLeft, t.style.MidMid, t.style.MidRight))
+		}
+	}
+	for rowIndex, row := range t.rows {
+		cells := make([]string, numCols)
+		for col := range cells {
+			column := t.columns[col]
+			cell := getCell(row, col)
+			if column.Colorizer != nil {
+				cell = column.Colorizer(cell)
+			}
+			if t.cellColorizer != nil {
+				cell = t.cellColorizer(rowIndex, col, cell)
+			}
+			// NOTE: truncation preserves the colors.
+			cell = TruncateWithEllipsis(cell, widths[col])
+			cells[col] = Pad(cell, widths[col], column.Align)
+		}
+		buf.WriteString(t.line(cells))
+	}
+	if t.style.Borders {
+		buf.WriteString(t.borderLine(widths, t.style.BottomLeft, t.style.BottomMid, t.style.BottomRight))
+	}
+	return buf.String()
+}
+
+func (t *Table) line(cells []string) string {
+	if t.style.Borders {
+		v := t.style.Vertical
+		return v + " " + strings.Join(cells, " "+v+" ") + " " + v + "\n"
+	}
+	return strings.TrimRight(strings.Join(cells, t.style.ColumnSeparator), " ") + "\n"
+}
+
+func (t *Table) borderLine(widths []int, left, mid, right string) string {
+	segments := make([]string, len(widths))
+	for i, w := range widths {
+		segments[i] = RepeatString(w+2, t.style.Horizontal)
+	}
+	return left + strings.Join(segments, mid) + right + "\n"
+}
+
+// RenderCSV writes the table (headers included) as CSV to w;
+// ANSI escape sequences are removed from the cells.
+func (t *Table) RenderCSV(w io.Writer) error {
+	numCols := t.numColumns()
+	writer := csv.NewWriter(w)
+	if len(t.headers) > 0 {
+		if err := writer.Write(t.plainRow(t.headers, numCols)); err != nil {
+			return err
+		}
+	}
+	for _, row := range t.rows {
+		if err := writer.Write(t.plainRow(row, numCols)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// RenderMarkdown writes the table as a GitHub-flavored Markdown table to w;
+// ANSI escape sequences are removed from the cells, and the
+// column alignments are preserved.
+func (t *Table) RenderMarkdown(w io.Writer) error {
+	numCols := t.numColumns()
+	t.ensureColumns(numCols)
+
+	escape := func(cells []string) []string {
+		for i := range cells {
+			cells[i] = strings.Replace(cells[i], "|", `\|`, -1)
+			cells[i] = strings.Replace(cells[i], "\n", " ", -1)
+		}
+		return cells
+	}
+
+	buf := new(strings.Builder)
+	buf.WriteString("| " + strings.Join(escape(t.plainRow(t.headers, numCols)), " | ") + " |\n")
+
+	separators := make([]string, numCols)
+	for col := range separators {
+		switch t.columns[col].Align {
+		case AlignRight:
+			separators[col] = "---:"
+		case AlignCenter:
+			separators[col] = ":---:"
+		default:
+			separators[col] = "---"
+		}
+	}
+	buf.WriteString("| " + strings.Join(separators, " | ") + " |\n")
+
+	for _, row := range t.rows {
+		buf.WriteString("| " + strings.Join(escape(t.plainRow(row, numCols)), " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func (t *Table) plainRow(row []string, numCols int) []string {
+	cells := make([]string, numCols)
+	for col := range cells {
+		cells[col] = StripANSI(getCell(row, col))
+	}
+	return cells
+}
@@ -0,0 +1,338 @@
+package utilz
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRange is a range of time, from From (inclusive) to To (exclusive).
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains returns true if t is in the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return !t.Before(r.From) && t.Before(r.To)
+}
+
+// Duration returns the duration of the range.
+func (r TimeRange) Duration() time.Duration {
+	return r.To.Sub(r.From)
+}
+
+func (r TimeRange) String() string {
+	return r.From.Format(time.RFC3339) + ".." + r.To.Format(time.RFC3339)
+}
+
+// ErrInvalidTime is returned when a time expression can't be parsed.
+var ErrInvalidTime = errors.New("invalid time")
+
+// absoluteTimeLayouts are the layouts of the absolute times accepted by ParseTime,
+// and whether they only specify a date.
+var absoluteTimeLayouts = []struct {
+	layout   string
+	wholeDay bool
+}{
+	{time.RFC3339Nano, false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02", true},
+	{FilenameTimeFormat, false},
+}
+
+// relativeTimeUnits maps the units of the relative expressions to their size;
+// months are calendar months (see addRelativeUnit).
+var relativeTimeUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": Day, "day": Day, "days": Day,
+	"w": Week, "wk": Week, "wks": Week, "week": Week, "weeks": Week,
+	"mo": 30 * Day, "month": 30 * Day, "months": 30 * Day,
+	"y": Year, "yr": Year, "yrs": Year, "year": Year, "years": Year,
+}
+
+var (
+	relativeAmountRegex = regexp.MustCompile(`^(\d+|an?)\s*([a-z]+)$`)
+	weekdays            = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+)
+
+// ParseTime parses an absolute or relative time expression; the relative
+// expressions are relative to now (and in its location). Accepted expressions:
+//
+//   - "now", "today", "yesterday", "tomorrow" (the last three at midnight);
+//   - "3d ago", "2 hours ago", "an hour ago", "in 15m", "now-15m", "now+1h";
+//     a bare amount like "2h" means "2h ago". Days, weeks, months and years
+//     are calendar units; compact durations like "1h30m" are accepted too
+//     (see ParseDurationExtended);
+//   - "monday" (the most recent one, today included), "last monday",
+//     "next monday" (at midnight);
+//   - absolute times: RFC3339, "2006-01-02", "2006-01-02 15:04[:05]",
+//     FilenameTimeFormat, "15:04[:05]" (today), "@1700000000" (unix seconds).
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	t, _, err := parseTimeExpression(s, now)
+	return t, err
+}
+
+// ParseTimeRange parses a range "from..to" where from and to are expressions
+// accepted by ParseTime; if to is empty, it is now. A single expression is
+// the range from that time to now, unless it is a whole day (e.g. "yesterday",
+// "last monday", "2024-01-01"), in which case the range is that day.
+func ParseTimeRange(s string, now time.Time) (TimeRange, error) {
+	if fromExpr, toExpr, ok := strings.Cut(s, ".."); ok {
+		from, _, err := parseTimeExpression(fromExpr, now)
+		if err != nil {
+			return TimeRange{}, err
+		}
+		to := now
+		if strings.TrimSpace(toExpr) != "" {
+			to, _, err = parseTimeExpression(toExpr, now)
+			if err != nil {
+				return TimeRange{}, err
+			}
+		}
+		if to.Before(from) {
+			return TimeRange{}, fmt.Errorf("invalid time range %q: the end is before the start", s)
+		}
+		return TimeRange{From: from, To: to}, nil
+	}
+
+	t, wholeDay, err := parseTimeExpression(s, now)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	if wholeDay {
+		return TimeRange{From: t, To: t.AddDate(0, 0, 1)}, nil
+	}
+	if now.Before(t) {
+		return TimeRange{From: now, To: t}, nil
+	}
+	return TimeRange{From: t, To: now}, nil
+}
+
+// parseTimeExpression parses a time expression (see ParseTime); wholeDay is
+// true if the expression denotes a day (at midnight).
+func parseTimeExpression(s string, now time.Time) (t time.Time, wholeDay bool, err error) {
+	orig := strings.TrimSpace(s)
+	s = strings.Join(strings.Fields(strings.ToLower(orig)), " ")
+	invalid := func() (time.Time, bool, error) {
+		return time.Time{}, false, fmt.Errorf("%w %q", ErrInvalidTime, orig)
+	}
+	if s == "" {
+		return invalid()
+	}
+
+	today := startOfDay(now)
+	switch s {
+	case "now":
+		return now, false, nil
+	case "today":
+		return today, true, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true, nil
+	}
+
+	// now-15m, now+1h:
+	if rest := strings.TrimPrefix(s, "now"); rest != s {
+		rest = strings.TrimSpace(rest)
+		if rest == "" || (rest[0] != '-' && rest[0] != '+') {
+			return invalid()
+		}
+		sign := 1
+		if rest[0] == '-' {
+			sign = -1
+		}
+		t, ok := addRelativeAmount(now, strings.TrimSpace(rest[1:]), sign)
+		if !ok {
+			return invalid()
+		}
+		return t, false, nil
+	}
+	// 3d ago:
+	if amount := strings.TrimSuffix(s, "ago"); amount != s {
+		t, ok := addRelativeAmount(now, strings.TrimSpace(amount), -1)
+		if !ok {
+			return invalid()
+		}
+		return t, false, nil
+	}
+	// in 2 hours:
+	if amount := strings.TrimPrefix(s, "in "); amount != s {
+		t, ok := addRelativeAmount(now, amount, 1)
+		if !ok {
+			return invalid()
+		}
+		return t, false, nil
+	}
+
+	// weekdays:
+	if t, ok := parseWeekday(s, today); ok {
+		return t, true, nil
+	}
+
+	// absolute times:
+	if strings.HasPrefix(s, "@") {
+		seconds, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil {
+			return invalid()
+		}
+		return time.Unix(seconds, 0).In(now.Location()), false, nil
+	}
+	for _, layout := range absoluteTimeLayouts {
+		if t, err := time.ParseInLocation(layout.layout, orig, now.Location()); err == nil {
+			return t, layout.wholeDay, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if clock, err := time.Parse(layout, orig); err == nil {
+			year, month, day := today.Date()
+			return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location()), false, nil
+		}
+	}
+
+	// a bare amount means "ago":
+	if t, ok := addRelativeAmount(now, s, -1); ok {
+		return t, false, nil
+	}
+	return invalid()
+}
+
+// addRelativeAmount adds (sign = 1) or subtracts (sign = -1) an amount like
+// "3d", "2 hours", "an hour" or "1h30m" to t.
+func addRelativeAmount(t time.Time, amount string, sign int) (time.Time, bool) {
+	if match := relativeAmountRegex.FindStringSubmatch(amount); match != nil {
+		if unit, ok := relativeTimeUnits[match[2]]; ok {
+			n := 1
+			if match[1] != "a" && match[1] != "an" {
+				var err error
+				n, err = strconv.Atoi(match[1])
+				if err != nil {
+					return time.Time{}, false
+				}
+			}
+			return addRelativeUnit(t, sign*n, unit), true
+		}
+	}
+	d, err := ParseDurationExtended(amount)
+	if err != nil || d < 0 {
+		return time.Time{}, false
+	}
+	return t.Add(time.Duration(sign) * d), true
+}
+
+// addRelativeUnit adds n units to t; days, weeks, months and years
+// are added as calendar units.
+func addRelativeUnit(t time.Time, n int, unit time.Duration) time.Time {
+	switch unit {
+	case Day:
+		return t.AddDate(0, 0, n)
+	case Week:
+		return t.AddDate(0, 0, 7*n)
+	case 30 * Day:
+		return t.AddDate(0, n, 0)
+	case Year:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.Add(time.Duration(n) * unit)
+	}
+}
+
+// parseWeekday parses "monday", "last monday" or "next monday".
+func parseWeekday(s string, today time.Time) (time.Time, bool) {
+	direction, name, ok := strings.Cut(s, " ")
+	if !ok {
+		direction, name = "", s
+	}
+	weekday, ok := weekdays[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	daysBack := (int(today.Weekday()) - int(weekday) + 7) % 7
+	switch direction {
+	case "":
+		// the most recent one, today included.
+		return today.AddDate(0, 0, -daysBack), true
+	case "last":
+		if daysBack == 0 {
+			daysBack = 7
+		}
+		return today.AddDate(0, 0, -daysBack), true
+	case "next":
+		daysForward := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if daysForward == 0 {
+			daysForward = 7
+		}
+		return today.AddDate(0, 0, daysForward), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// startOfDay returns the midnight of the day of t, in its location.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// humanizeUnits are the units used by HumanizeDuration, largest first.
+var humanizeUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"year", Year},
+	{"month", 30 * Day},
+	{"week", Week},
+	{"day", Day},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+// HumanizeDuration formats d with its largest unit, rounded down,
+// e.g. "5 minutes" or "1 hour" (a month is 30 days).
+func HumanizeDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	for _, unit := range humanizeUnits {
+		if d >= unit.size {
+			n := int64(d / unit.size)
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return Sf("%d %ss", n, unit.name)
+		}
+	}
+	return "0 seconds"
+}
+
+// HumanizeTime formats t relative to now, e.g. "5 minutes ago",
+// "in 2 hours", or "just now" (if less than a second apart).
+func HumanizeTime(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d > -time.Second && d < time.Second:
+		return "just now"
+	case d > 0:
+		return HumanizeDuration(d) + " ago"
+	default:
+		return "in " + HumanizeDuration(d)
+	}
+}
@@ -0,0 +1,147 @@
+package utilz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testNow is a Monday.
+var testNow = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+func testDate(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseTime(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"now", testNow},
+		{"today", testDate(1, 15, 0, 0)},
+		{"yesterday", testDate(1, 14, 0, 0)},
+		{"tomorrow", testDate(1, 16, 0, 0)},
+		// a bare amount means "ago":
+		{"2h", testDate(1, 15, 8, 30)},
+		{"1h30m", testDate(1, 15, 9, 0)},
+		{"3d ago", testDate(1, 12, 10, 30)},
+		{"3 days ago", testDate(1, 12, 10, 30)},
+		{"an hour ago", testDate(1, 15, 9, 30)},
+		{"  2   Hours  AGO ", testDate(1, 15, 8, 30)},
+		{"1 month ago", time.Date(2023, 12, 15, 10, 30, 0, 0, time.UTC)},
+		{"1y ago", time.Date(2023, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"in 15m", testDate(1, 15, 10, 45)},
+		{"in 2 weeks", testDate(1, 29, 10, 30)},
+		{"now-15m", testDate(1, 15, 10, 15)},
+		{"now - 1d", testDate(1, 14, 10, 30)},
+		{"now+1h", testDate(1, 15, 11, 30)},
+		// weekdays; today is a Monday:
+		{"monday", testDate(1, 15, 0, 0)},
+		{"last monday", testDate(1, 8, 0, 0)},
+		{"next monday", testDate(1, 22, 0, 0)},
+		{"sunday", testDate(1, 14, 0, 0)},
+		{"last sun", testDate(1, 14, 0, 0)},
+		{"next tuesday", testDate(1, 16, 0, 0)},
+		{"friday", testDate(1, 12, 0, 0)},
+		{"next friday", testDate(1, 19, 0, 0)},
+		// absolute times:
+		{"2024-01-02", testDate(1, 2, 0, 0)},
+		{"2024-01-02 15:04", testDate(1, 2, 15, 4)},
+		{"2024-01-02T15:04:05Z", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"08:15", testDate(1, 15, 8, 15)},
+		{"@1700000000", time.Unix(1700000000, 0).UTC()},
+	}
+	for _, c := range cases {
+		got, err := ParseTime(c.s, testNow)
+		if err != nil || !got.Equal(c.expected) {
+			t.Errorf("ParseTime(%q) = %v, %v; expected %v", c.s, got, err, c.expected)
+		}
+	}
+}
+
+func TestParseTimeInvalid(t *testing.T) {
+	for _, s := range []string{"", "   ", "soon", "now-", "now*2h", "3x ago", "in", "last", "last month day", "next blursday", "-2h", "@abc", "2024-13-01"} {
+		if got, err := ParseTime(s, testNow); !errors.Is(err, ErrInvalidTime) {
+			t.Errorf("ParseTime(%q) = %v, %v; expected ErrInvalidTime", s, got, err)
+		}
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected TimeRange
+	}{
+		{"2024-01-01..2024-01-10", TimeRange{testDate(1, 1, 0, 0), testDate(1, 10, 0, 0)}},
+		{"3d ago..1d ago", TimeRange{testDate(1, 12, 10, 30), testDate(1, 14, 10, 30)}},
+		{"last monday..today", TimeRange{testDate(1, 8, 0, 0), testDate(1, 15, 0, 0)}},
+		// an open end is now:
+		{"2h..", TimeRange{testDate(1, 15, 8, 30), testNow}},
+		{"yesterday ..  ", TimeRange{testDate(1, 14, 0, 0), testNow}},
+		// a single time is the range up to now:
+		{"15m", TimeRange{testDate(1, 15, 10, 15), testNow}},
+		{"in 1h", TimeRange{testNow, testDate(1, 15, 11, 30)}},
+		// a whole day is that day:
+		{"yesterday", TimeRange{testDate(1, 14, 0, 0), testDate(1, 15, 0, 0)}},
+		{"last monday", TimeRange{testDate(1, 8, 0, 0), testDate(1, 9, 0, 0)}},
+		{"2024-01-02", TimeRange{testDate(1, 2, 0, 0), testDate(1, 3, 0, 0)}},
+	}
+	for _, c := range cases {
+		got, err := ParseTimeRange(c.s, testNow)
+		if err != nil || !got.From.Equal(c.expected.From) || !got.To.Equal(c.expected.To) {
+			t.Errorf("ParseTimeRange(%q) = %v, %v; expected %v", c.s, got, err, c.expected)
+		}
+	}
+
+	for _, s := range []string{"1d ago..3d ago", "tomorrow..today"} {
+		if got, err := ParseTimeRange(s, testNow); err == nil {
+			t.Errorf("ParseTimeRange(%q) = %v; expected an error for a reversed range", s, got)
+		}
+	}
+	for _, s := range []string{"..", "..today", "soon..", "today..soon"} {
+		if got, err := ParseTimeRange(s, testNow); !errors.Is(err, ErrInvalidTime) {
+			t.Errorf("ParseTimeRange(%q) = %v, %v; expected ErrInvalidTime", s, got, err)
+		}
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	r := TimeRange{testDate(1, 14, 0, 0), testDate(1, 15, 0, 0)}
+	if r.Duration() != Day {
+		t.Fatalf("got %v", r.Duration())
+	}
+	if !r.Contains(r.From) || r.Contains(r.To) || r.Contains(r.From.Add(-time.Nanosecond)) {
+		t.Fatal("From must be included and To excluded")
+	}
+	if got := r.String(); got != "2024-01-14T00:00:00Z..2024-01-15T00:00:00Z" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestHumanizeTime(t *testing.T) {
+	cases := []struct {
+		d        time.Duration // now - t
+		expected string
+	}{
+		{0, "just now"},
+		{999 * time.Millisecond, "just now"},
+		{-999 * time.Millisecond, "just now"},
+		{time.Second, "1 second ago"},
+		{-time.Second, "in 1 second"},
+		{59 * time.Second, "59 seconds ago"},
+		{90 * time.Minute, "1 hour ago"},
+		{-2 * time.Hour, "in 2 hours"},
+		{13 * Day, "1 week ago"},
+		{45 * Day, "1 month ago"},
+		{-2 * Year, "in 2 years"},
+	}
+	for _, c := range cases {
+		if got := HumanizeTime(testNow.Add(-c.d), testNow); got != c.expected {
+			t.Errorf("HumanizeTime(now - %v) = %q, expected %q", c.d, got, c.expected)
+		}
+	}
+	if got := HumanizeDuration(0); got != "0 seconds" {
+		t.Errorf("HumanizeDuration(0) = %q", got)
+	}
+}
@@ -1,6 +1,9 @@
 package utilz
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 const (
 	FilenameTimeFormat = "Mon02Jan2006_15.04.05"
@@ -11,33 +14,56 @@ const (
 // and at each retry it will sleep double the amout of time of the previous retry;
 // the initial sleep time is specified by the user.
 func RetryExponentialBackoff(attempts int, initialSleep time.Duration, task func() error) []error {
-	var errs []error
-	for i := 1; i <= attempts; i++ {
-		err := task()
-		if err == nil {
-			// if one of the runs succeeds, do not return any previous errors
-			return nil
-		}
-		errs = append(errs, err)
-		time.Sleep(initialSleep)
-		initialSleep = initialSleep * 2
+	return RetryExponentialBackoffWithClock(attempts, initialSleep, task, nil)
+}
+
+// RetryExponentialBackoffWithClock is like RetryExponentialBackoff,
+// but sleeps using the provided clock.
+func RetryExponentialBackoffWithClock(attempts int, initialSleep time.Duration, task func() error, clock Clock) []error {
+	if attempts <= 0 {
+		return nil
 	}
-	return errs
+	return Retry(
+		context.Background(),
+		RetryPolicy{
+			MaxAttempts:  attempts,
+			InitialDelay: initialSleep,
+			Multiplier:   2,
+			Clock:        clock,
+		},
+		func(_ context.Context) error {
+			return task()
+		},
+	)
 }
 
+// RetryLinearBackoff executes task; if it returns an error,
+// it will retry executing the task the specified number of times before giving up,
+// sleeping the same amount of time before each retry.
 func RetryLinearBackoff(attempts int, sleep time.Duration, task func() error) []error {
-	var errs []error
-	for i := 1; i <= attempts; i++ {
-		err := task()
-		if err == nil {
-			// if one of the runs succeeds, do not return any previous errors
-			return nil
-		}
-		errs = append(errs, err)
-		time.Sleep(sleep)
+	return RetryLinearBackoffWithClock(attempts, sleep, task, nil)
+}
+
+// RetryLinearBackoffWithClock is like RetryLinearBackoff,
+// but sleeps using the provided clock.
+func RetryLinearBackoffWithClock(attempts int, sleep time.Duration, task func() error, clock Clock) []error {
+	if attempts <= 0 {
+		return nil
 	}
-	return errs
+	return Retry(
+		context.Background(),
+		RetryPolicy{
+			MaxAttempts:  attempts,
+			InitialDelay: sleep,
+			Multiplier:   1,
+			Clock:        clock,
+		},
+		func(_ context.Context) error {
+			return task()
+		},
+	)
 }
+
 func FormatErrorArray(prefix string, errs []error) string {
 	var res string
 	for i, err := range errs {
@@ -58,24 +84,64 @@ func FormatErrorArray(prefix string, errs []error) string {
 	return res
 }
 func KitchenTimeNow() string {
-	return time.Now().Format("15:04:05")
+	return KitchenTimeNowWithClock(nil)
 }
 func KitchenTimeMsNow() string {
-	return time.Now().Format("15:04:05.999")
+	return KitchenTimeMsNowWithClock(nil)
+}
+
+// KitchenTimeNowWithClock is like KitchenTimeNow, but uses the provided clock.
+func KitchenTimeNowWithClock(clock Clock) string {
+	return orRealClock(clock).Now().Format("15:04:05")
+}
+
+// KitchenTimeMsNowWithClock is like KitchenTimeMsNow, but uses the provided clock.
+func KitchenTimeMsNowWithClock(clock Clock) string {
+	return orRealClock(clock).Now().Format("15:04:05.999")
 }
 
 // NewTicker returns a time channel that ticks ever specified interval,
 // with the initial tick righ when you start listening.
+// The ticker can't be stopped; use NewTickerContext for that.
 func NewTicker(duration time.Duration) <-chan time.Time {
+	return NewTickerWithClock(duration, nil)
+}
+
+// NewTickerWithClock is like NewTicker, but uses the provided clock.
+func NewTickerWithClock(duration time.Duration, clock Clock) <-chan time.Time {
+	return NewTickerContextWithClock(context.Background(), duration, clock)
+}
+
+// NewTickerContext is like NewTicker, but the ticker is stopped (and
+// the channel closed) when ctx is done. If the reader is slow, the ticks
+// are dropped instead of piling up.
+func NewTickerContext(ctx context.Context, duration time.Duration) <-chan time.Time {
+	return NewTickerContextWithClock(ctx, duration, nil)
+}
+
+// NewTickerContextWithClock is like NewTickerContext, but uses the provided clock.
+func NewTickerContextWithClock(ctx context.Context, duration time.Duration, clock Clock) <-chan time.Time {
+	clock = orRealClock(clock)
 	c := make(chan time.Time, 1)
 
 	go func() {
-		c <- time.Now()
-		ticker := time.NewTicker(duration)
-
-		for tick := range ticker.C {
-			// Ticks immediately and every duration thereafter
-			c <- tick
+		defer close(c)
+		ticker := clock.NewTicker(duration)
+		defer ticker.Stop()
+
+		// Ticks immediately and every duration thereafter
+		c <- clock.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case tick := <-ticker.C():
+				select {
+				case c <- tick:
+				default:
+					// the reader is slow; drop the tick.
+				}
+			}
 		}
 	}()
 
@@ -90,24 +156,33 @@ type Ticker interface {
 }
 
 type ticker struct {
-	*time.Ticker
+	ClockTicker
 	d time.Duration
 }
 
-func (t *ticker) Tick()                   { <-t.C }
+func (t *ticker) Tick()                   { <-t.C() }
 func (t *ticker) Duration() time.Duration { return t.d }
 
 func NewTickerCountdown(d time.Duration) Ticker {
-	return &ticker{time.NewTicker(d), d}
+	return NewTickerCountdownWithClock(d, nil)
+}
+
+// NewTickerCountdownWithClock is like NewTickerCountdown, but uses the provided clock.
+func NewTickerCountdownWithClock(d time.Duration, clock Clock) Ticker {
+	return &ticker{orRealClock(clock).NewTicker(d), d}
 }
 
 type TickFunc func(d time.Duration)
 
+// Countdown sends on the returned channel the remaining time at each tick,
+// until the duration has passed; if the reader is slow, the stale values
+// are replaced by the latest one. See NewCountdown for a countdown
+// that can be stopped and paused.
 func Countdown(ticker Ticker, duration time.Duration) chan time.Duration {
 	remainingCh := make(chan time.Duration, 1)
 	go func(ticker Ticker, dur time.Duration, remainingCh chan time.Duration) {
 		for remaining := duration; remaining >= 0; remaining -= ticker.Duration() {
-			remainingCh <- remaining
+			sendLatest(remainingCh, remaining)
 			ticker.Tick()
 		}
 		ticker.Stop()
@@ -116,15 +191,42 @@ func Countdown(ticker Ticker, duration time.Duration) chan time.Duration {
 	return remainingCh
 }
 func NewTimer() func() time.Duration {
-	start := time.Now()
+	return NewTimerWithClock(nil)
+}
+
+func NewTimerRaw() func() time.Duration {
+	return NewTimerRawWithClock(nil)
+}
+
+// NewTimerWithClock is like NewTimer, but uses the provided clock.
+func NewTimerWithClock(clock Clock) func() time.Duration {
+	elapsed := NewTimerRawWithClock(clock)
 	return func() time.Duration {
-		return time.Now().Sub(start).Round(time.Second)
+		return elapsed().Round(time.Second)
 	}
 }
 
-func NewTimerRaw() func() time.Duration {
-	start := time.Now()
+// NewTimerRawWithClock is like NewTimerRaw, but uses the provided clock.
+func NewTimerRawWithClock(clock Clock) func() time.Duration {
+	clock = orRealClock(clock)
+	start := clock.Now()
 	return func() time.Duration {
-		return time.Now().Sub(start)
+		return clock.Now().Sub(start)
+	}
+}
+
+// sendLatest sends v on ch (which must be buffered) without blocking;
+// if ch is full, the stale value is dropped. There must be only one sender.
+func sendLatest[T any](ch chan T, v T) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+		}
 	}
 }
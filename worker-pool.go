@@ -0,0 +1,118 @@
+package utilz
+
+import (
+	"context"
+	"sync"
+)
+
+// MapResult is the result of processing one item with MapStream or MapResults.
+type MapResult[T, R any] struct {
+	// Index is the index of the item in the input slice.
+	Index int
+	Item  T
+	Value R
+	// Err is the error returned by fn (a *PanicError if fn panicked),
+	// or the error of the context if the item was not processed.
+	Err error
+}
+
+// Map calls fn for each item, running at most concurrency calls at the same
+// time (all of them if concurrency <= 0), and returns the results in the same
+// order as the items. The first error cancels the context passed to the other
+// calls, and is returned.
+func Map[T, R any](ctx context.Context, items []T, concurrency int, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	values := make([]R, len(items))
+	var firstErr error
+	for res := range MapStream(ctx, items, concurrency, true, fn) {
+		if res.Err != nil {
+			// the results arrive in the order in which they finished,
+			// so the first error is the one that canceled the others.
+			if firstErr == nil {
+				firstErr = res.Err
+			}
+			continue
+		}
+		values[res.Index] = res.Value
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return values, nil
+}
+
+// MapResults is like Map, but returns the result (and the error) of each item,
+// in the same order as the items. If stopOnError is true, the first error
+// cancels the context passed to the other calls, and the items that were
+// not processed yet get the error of the context.
+func MapResults[T, R any](ctx context.Context, items []T, concurrency int, stopOnError bool, fn func(ctx context.Context, item T) (R, error)) []MapResult[T, R] {
+	results := make([]MapResult[T, R], len(items))
+	for res := range MapStream(ctx, items, concurrency, stopOnError, fn) {
+		results[res.Index] = res
+	}
+	return results
+}
+
+// MapStream is like MapResults, but sends the results on the returned channel
+// as soon as they are ready; the channel is closed when all the items have
+// been processed. The channel is buffered to hold all the results, so
+// the workers never leak even if the consumer stops reading.
+func MapStream[T, R any](ctx context.Context, items []T, concurrency int, stopOnError bool, fn func(ctx context.Context, item T) (R, error)) <-chan MapResult[T, R] {
+	out := make(chan MapResult[T, R], len(items))
+	if len(items) == 0 {
+		close(out)
+		return out
+	}
+	if concurrency <= 0 || concurrency > len(items) {
+		concurrency = len(items)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	indexes := make(chan int)
+
+	// feed the indexes of the items to the workers:
+	go func() {
+		defer close(indexes)
+		for i := range items {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				// report the items that will not be processed:
+				for ; i < len(items); i++ {
+					out <- MapResult[T, R]{Index: i, Item: items[i], Err: ctx.Err()}
+				}
+				return
+			}
+		}
+	}()
+
+	wg := new(sync.WaitGroup)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				res := MapResult[T, R]{Index: i, Item: items[i]}
+				if err := ctx.Err(); err != nil {
+					res.Err = err
+				} else {
+					res.Err = callRecover(func() error {
+						var err error
+						res.Value, err = fn(ctx, items[i])
+						return err
+					})
+				}
+				out <- res
+				if res.Err != nil && stopOnError {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+	return out
+}